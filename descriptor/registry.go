@@ -23,6 +23,10 @@ type Registry struct {
 	// files is a mapping from file path to descriptor
 	files map[string]*File
 
+	// resources is a mapping from resource type to the resource declared by google.api.resource
+	// or google.api.resource_definition
+	resources map[string]*Resource
+
 	// prefix is a prefix to be inserted to golang package paths generated from proto package names.
 	prefix string
 
@@ -99,6 +103,7 @@ func NewRegistry() *Registry {
 		msgs:              make(map[string]*Message),
 		enums:             make(map[string]*Enum),
 		files:             make(map[string]*File),
+		resources:         make(map[string]*Resource),
 		pkgMap:            make(map[string]string),
 		pkgAliases:        make(map[string]string),
 		externalHTTPRules: make(map[string][]*annotations.HttpRule),
@@ -162,6 +167,36 @@ func (r *Registry) loadFile(file *descriptor.FileDescriptorProto) {
 	r.files[file.GetName()] = f
 	r.registerMsg(f, nil, file.GetMessageType())
 	r.registerEnum(f, nil, file.GetEnumType())
+	r.registerResources(f)
+}
+
+// registerResources registers the resources declared by "file" and by its messages.
+func (r *Registry) registerResources(file *File) {
+	defs, err := extractResourceDefinitions(file.FileDescriptorProto)
+	if err != nil {
+		glog.Warningf("Failed to extract google.api.resource_definition from %s: %v", file.GetName(), err)
+	}
+	for _, def := range defs {
+		r.registerResource(&Resource{ResourceDescriptor: def})
+	}
+	for _, m := range file.Messages {
+		def, err := extractResource(m.DescriptorProto)
+		if err != nil {
+			glog.Warningf("Failed to extract google.api.resource from %s: %v", m.FQMN(), err)
+		}
+		if def != nil {
+			m.Resource = &Resource{ResourceDescriptor: def, Message: m}
+			r.registerResource(m.Resource)
+		}
+	}
+}
+
+func (r *Registry) registerResource(res *Resource) {
+	if res.GetType() == "" {
+		return
+	}
+	r.resources[res.GetType()] = res
+	glog.V(1).Infof("register resource type: %s", res.GetType())
 }
 
 func (r *Registry) registerMsg(file *File, outerPath []string, msgs []*descriptor.DescriptorProto) {
@@ -256,6 +291,15 @@ func (r *Registry) LookupEnum(location, name string) (*Enum, error) {
 	return nil, fmt.Errorf("no enum found: %s", name)
 }
 
+// LookupResource looks up a resource by its type, e.g. "library.googleapis.com/Book".
+func (r *Registry) LookupResource(typ string) (*Resource, error) {
+	res, ok := r.resources[typ]
+	if !ok {
+		return nil, fmt.Errorf("no resource found: %s", typ)
+	}
+	return res, nil
+}
+
 // LookupFile looks up a file by name.
 func (r *Registry) LookupFile(name string) (*File, error) {
 	f, ok := r.files[name]
@@ -616,6 +616,62 @@ func TestUnboundExternalHTTPRules(t *testing.T) {
 	assertStringSlice(t, "unbound external HTTP rules", reg.UnboundExternalHTTPRules(), []string{})
 }
 
+func TestLookupResource(t *testing.T) {
+	reg := NewRegistry()
+	loadFile(t, reg, `
+		name: "path/to/example.proto",
+		package: "example"
+		dependency: "google/api/resource.proto"
+		options <
+			[google.api.resource_definition] <
+				type: "example.com/Publisher"
+				pattern: "publishers/{publisher}"
+			>
+		>
+		message_type <
+			name: "Book"
+			field <
+				name: "name"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+			>
+			options <
+				[google.api.resource] <
+					type: "example.com/Book"
+					pattern: "publishers/{publisher}/books/{book}"
+				>
+			>
+		>
+	`)
+
+	for _, spec := range []struct {
+		typ      string
+		message  string
+		patterns []string
+	}{
+		{typ: "example.com/Publisher", patterns: []string{"publishers/{publisher}"}},
+		{typ: "example.com/Book", message: ".example.Book", patterns: []string{"publishers/{publisher}/books/{book}"}},
+	} {
+		res, err := reg.LookupResource(spec.typ)
+		if err != nil {
+			t.Errorf("reg.LookupResource(%q) failed with %v; want success", spec.typ, err)
+			continue
+		}
+		var message string
+		if res.Message != nil {
+			message = res.Message.FQMN()
+		}
+		if message != spec.message {
+			t.Errorf("reg.LookupResource(%q).Message = %q; want %q", spec.typ, message, spec.message)
+		}
+		assertStringSlice(t, "resource patterns", res.GetPattern(), spec.patterns)
+	}
+	if res, err := reg.LookupResource("example.com/Shelf"); err == nil {
+		t.Errorf("reg.LookupResource(%q) = %v; want an error", "example.com/Shelf", res)
+	}
+}
+
 func assertStringSlice(t *testing.T, message string, got, want []string) {
 	if len(got) != len(want) {
 		t.Errorf("%s = %#v len(%d); want %#v len(%d)", message, got, len(got), want, len(want))
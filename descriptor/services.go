@@ -9,6 +9,8 @@ import (
 	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
 	options "google.golang.org/genproto/googleapis/api/annotations"
+
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
 )
 
 // loadServices registers services and their methods from "targetFile" to "r".
@@ -19,9 +21,15 @@ func (r *Registry) loadServices(file *File) error {
 	var svcs []*Service
 	for _, sd := range file.GetService() {
 		glog.V(2).Infof("Registering %s", sd.GetName())
+		cors, err := extractServiceCORS(sd)
+		if err != nil {
+			glog.Errorf("Failed to extract gokitmux.service_cors from %s: %v", sd.GetName(), err)
+			return err
+		}
 		svc := &Service{
 			File:                   file,
 			ServiceDescriptorProto: sd,
+			CORS:                   cors,
 		}
 		for _, md := range sd.GetMethod() {
 			glog.V(2).Infof("Processing %s.%s", sd.GetName(), md.GetName())
@@ -63,11 +71,21 @@ func (r *Registry) newMethod(
 	if err != nil {
 		return nil, err
 	}
+	status, err := extractResponseStatus(md)
+	if err != nil {
+		return nil, err
+	}
+	cors, err := extractMethodCORS(md)
+	if err != nil {
+		return nil, err
+	}
 	meth := &Method{
 		Service:               svc,
 		MethodDescriptorProto: md,
 		RequestType:           requestType,
 		ResponseType:          responseType,
+		ResponseStatus:        status,
+		CORS:                  cors,
 	}
 
 	newBinding := func(opts *options.HttpRule, idx int) (*Binding, error) {
@@ -152,6 +170,16 @@ func (r *Registry) newMethod(
 			return nil, err
 		}
 
+		b.HeaderParams, err = r.newHeaderParams(meth, b)
+		if err != nil {
+			return nil, err
+		}
+
+		b.FieldBehaviors, err = r.newFieldBehaviors(meth.RequestType, nil, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+
 		return b, nil
 	}
 
@@ -209,6 +237,54 @@ func extractAPIOptions(meth *descriptor.MethodDescriptorProto) (*options.HttpRul
 	return opts, nil
 }
 
+// extractResponseStatus returns the gokitmux.status option of "meth", or nil if it has none.
+func extractResponseStatus(meth *descriptor.MethodDescriptorProto) (*gmoptions.ResponseStatus, error) {
+	if meth.Options == nil || !proto.HasExtension(meth.Options, gmoptions.E_Status) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(meth.Options, gmoptions.E_Status)
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := ext.(*gmoptions.ResponseStatus)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a ResponseStatus", ext)
+	}
+	return opts, nil
+}
+
+// extractServiceCORS returns the gokitmux.service_cors option of "svc", or nil if it has none.
+func extractServiceCORS(svc *descriptor.ServiceDescriptorProto) (*gmoptions.CORSPolicy, error) {
+	if svc.Options == nil || !proto.HasExtension(svc.Options, gmoptions.E_ServiceCors) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(svc.Options, gmoptions.E_ServiceCors)
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := ext.(*gmoptions.CORSPolicy)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a CORSPolicy", ext)
+	}
+	return opts, nil
+}
+
+// extractMethodCORS returns the gokitmux.cors option of "meth", or nil if it has none.
+func extractMethodCORS(meth *descriptor.MethodDescriptorProto) (*gmoptions.CORSPolicy, error) {
+	if meth.Options == nil || !proto.HasExtension(meth.Options, gmoptions.E_Cors) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(meth.Options, gmoptions.E_Cors)
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := ext.(*gmoptions.CORSPolicy)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a CORSPolicy", ext)
+	}
+	return opts, nil
+}
+
 func (r *Registry) newParam(meth *Method, path string) (Parameter, error) {
 	msg := meth.RequestType
 	fields, err := r.resolveFieldPath(msg, path, true)
@@ -235,13 +311,212 @@ func (r *Registry) newParam(meth *Method, path string) (Parameter, error) {
 			)
 		}
 	}
+	ref, err := r.newResourceReference(target)
+	if err != nil {
+		return Parameter{}, err
+	}
 	return Parameter{
 		FieldPath: FieldPath(fields),
 		Method:    meth,
 		Target:    fields[l-1].Target,
+		Resource:  ref,
 	}, nil
 }
 
+// newResourceReference returns the resource type whose names "field" holds: the one its
+// google.api.resource_reference refers to, or the resource of its message if it is the name field of it.
+// It returns nil if the field refers to any resource type, or to one not declared in the loaded files.
+func (r *Registry) newResourceReference(field *Field) (*ResourceReference, error) {
+	opts, err := extractResourceReference(field.FieldDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		res := field.Message.Resource
+		if res == nil || res.NameField() != field.GetName() {
+			return nil, nil
+		}
+		return &ResourceReference{Resource: res}, nil
+	}
+	typ, parent := opts.GetType(), false
+	if typ == "" {
+		typ, parent = opts.GetChildType(), true
+	}
+	if typ == "*" {
+		return nil, nil
+	}
+	res, err := r.LookupResource(typ)
+	if err != nil {
+		glog.V(1).Infof("Resource reference of %s in %s is not resolved: %v", field.GetName(), field.Message.FQMN(), err)
+		return nil, nil
+	}
+	return &ResourceReference{Resource: res, Parent: parent}, nil
+}
+
+// newHeaderParams returns the parameters of "b" provided in headers and cookies.
+// They are the top-level scalar fields of the request with the gokitmux.binding option
+// which are not bound to the path or the body field of "b".
+func (r *Registry) newHeaderParams(meth *Method, b *Binding) ([]HeaderParameter, error) {
+	var result []HeaderParameter
+	for _, f := range meth.RequestType.Fields {
+		opts, err := extractFieldBinding(f.FieldDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		if opts == nil {
+			continue
+		}
+		if opts.GetHeader() == "" && opts.GetCookie() == "" {
+			return nil, fmt.Errorf("gokitmux.binding of %s in %s names no header or cookie", f.GetName(), meth.RequestType.GetName())
+		}
+		switch {
+		case f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED,
+			f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+			f.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP,
+			f.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM,
+			f.OneofIndex != nil && !f.GetProto3Optional():
+			return nil, fmt.Errorf("field %s in %s cannot be bound to a header or cookie, only singular scalar fields can", f.GetName(), meth.RequestType.GetName())
+		}
+		for _, p := range b.PathParams {
+			if p.FieldPath[0].Name == f.GetName() {
+				return nil, fmt.Errorf("field %s in %s is bound to both the path and a header or cookie in %s.%s", f.GetName(), meth.RequestType.GetName(), meth.Service.GetName(), meth.GetName())
+			}
+		}
+		if b.Body != nil && len(b.Body.FieldPath) != 0 && b.Body.FieldPath[0].Name == f.GetName() {
+			return nil, fmt.Errorf("field %s in %s is bound to both the body and a header or cookie in %s.%s", f.GetName(), meth.RequestType.GetName(), meth.Service.GetName(), meth.GetName())
+		}
+		result = append(result, HeaderParameter{
+			Parameter: Parameter{
+				FieldPath: FieldPath{{Name: f.GetName(), Target: f}},
+				Target:    f,
+				Method:    meth,
+			},
+			Header:   opts.GetHeader(),
+			Cookie:   opts.GetCookie(),
+			Required: opts.GetRequired(),
+		})
+	}
+	return result, nil
+}
+
+// extractFieldBinding returns the gokitmux.binding option of "field", or nil if it has none.
+func extractFieldBinding(field *descriptor.FieldDescriptorProto) (*gmoptions.FieldBinding, error) {
+	if field.Options == nil || !proto.HasExtension(field.Options, gmoptions.E_Binding) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(field.Options, gmoptions.E_Binding)
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := ext.(*gmoptions.FieldBinding)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a FieldBinding", ext)
+	}
+	return opts, nil
+}
+
+// newFieldBehaviors returns the fields of "msg" annotated with google.api.field_behavior, their paths
+// prefixed with "prefix". Singular message fields are searched recursively, except for the messages in
+// "enclosing", which encloses "msg", and for the types of google.protobuf.
+func (r *Registry) newFieldBehaviors(msg *Message, prefix FieldPath, enclosing map[string]bool) ([]FieldBehavior, error) {
+	enclosing[msg.FQMN()] = true
+	defer delete(enclosing, msg.FQMN())
+
+	var result []FieldBehavior
+	for _, f := range msg.Fields {
+		behaviors, err := extractFieldBehaviors(f.FieldDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		path := append(append(FieldPath{}, prefix...), FieldPathComponent{Name: f.GetName(), Target: f})
+		if len(behaviors) != 0 {
+			result = append(result, FieldBehavior{FieldPath: path, Behaviors: behaviors})
+		}
+		if f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE ||
+			f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED ||
+			strings.HasPrefix(f.GetTypeName(), ".google.protobuf.") {
+			continue
+		}
+		fieldMsg, err := r.LookupMsg(msg.FQMN(), f.GetTypeName())
+		if err != nil {
+			return nil, err
+		}
+		if enclosing[fieldMsg.FQMN()] {
+			continue
+		}
+		nested, err := r.newFieldBehaviors(fieldMsg, path, enclosing)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nested...)
+	}
+	return result, nil
+}
+
+// extractFieldBehaviors returns the google.api.field_behavior annotations of "field".
+func extractFieldBehaviors(field *descriptor.FieldDescriptorProto) ([]options.FieldBehavior, error) {
+	if field.Options == nil || !proto.HasExtension(field.Options, options.E_FieldBehavior) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(field.Options, options.E_FieldBehavior)
+	if err != nil {
+		return nil, err
+	}
+	behaviors, ok := ext.([]options.FieldBehavior)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a []FieldBehavior", ext)
+	}
+	return behaviors, nil
+}
+
+// extractResourceReference returns the google.api.resource_reference option of "field", or nil if it has none.
+func extractResourceReference(field *descriptor.FieldDescriptorProto) (*options.ResourceReference, error) {
+	if field.Options == nil || !proto.HasExtension(field.Options, options.E_ResourceReference) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(field.Options, options.E_ResourceReference)
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := ext.(*options.ResourceReference)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a ResourceReference", ext)
+	}
+	return opts, nil
+}
+
+// extractResource returns the google.api.resource option of "msg", or nil if it has none.
+func extractResource(msg *descriptor.DescriptorProto) (*options.ResourceDescriptor, error) {
+	if msg.Options == nil || !proto.HasExtension(msg.Options, options.E_Resource) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(msg.Options, options.E_Resource)
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := ext.(*options.ResourceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a ResourceDescriptor", ext)
+	}
+	return opts, nil
+}
+
+// extractResourceDefinitions returns the google.api.resource_definition options of "file".
+func extractResourceDefinitions(file *descriptor.FileDescriptorProto) ([]*options.ResourceDescriptor, error) {
+	if file.Options == nil || !proto.HasExtension(file.Options, options.E_ResourceDefinition) {
+		return nil, nil
+	}
+	ext, err := proto.GetExtension(file.Options, options.E_ResourceDefinition)
+	if err != nil {
+		return nil, err
+	}
+	defs, ok := ext.([]*options.ResourceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("extension is %T; want a []*ResourceDescriptor", ext)
+	}
+	return defs, nil
+}
+
 func (r *Registry) newBody(meth *Method, path string) (*Body, error) {
 	msg := meth.RequestType
 	switch path {
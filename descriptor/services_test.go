@@ -7,6 +7,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
+	options "google.golang.org/genproto/googleapis/api/annotations"
 )
 
 func compilePath(t *testing.T, path string) httprule.Template {
@@ -75,6 +76,9 @@ func testExtractServices(t *testing.T, input []*descriptor.FileDescriptorProto,
 							t.Errorf("svcs[%d].Methods[%d].Bindings[%d].PathParams[%d].FieldPath[%d].Target.FieldDescriptorProto = %v; want %v; input = %v", i, j, k, l, m, got, want, input)
 						}
 					}
+					if got, want := resourceReference(param.Resource), resourceReference(wantParam.Resource); got != want {
+						t.Errorf("svcs[%d].Methods[%d].Bindings[%d].PathParams[%d].Resource = %q; want %q; input = %v", i, j, k, l, got, want, input)
+					}
 				}
 				for ; l < len(binding.PathParams); l++ {
 					got := binding.PathParams[l].FieldPath.String()
@@ -96,6 +100,42 @@ func testExtractServices(t *testing.T, input []*descriptor.FileDescriptorProto,
 						t.Errorf("svcs[%d].Methods[%d].Bindings[%d].Body = %q; want %q; input = %v", i, j, k, got, want, input)
 					}
 				}
+
+				for l = 0; l < len(binding.HeaderParams) && l < len(wantBinding.HeaderParams); l++ {
+					param, wantParam := binding.HeaderParams[l], wantBinding.HeaderParams[l]
+					if got, want := param.FieldPath.String(), wantParam.FieldPath.String(); got != want {
+						t.Errorf("svcs[%d].Methods[%d].Bindings[%d].HeaderParams[%d].FieldPath.String() = %q; want %q; input = %v", i, j, k, l, got, want, input)
+					}
+					if got, want := [3]interface{}{param.Header, param.Cookie, param.Required}, [3]interface{}{wantParam.Header, wantParam.Cookie, wantParam.Required}; got != want {
+						t.Errorf("svcs[%d].Methods[%d].Bindings[%d].HeaderParams[%d] header, cookie, required = %v; want %v; input = %v", i, j, k, l, got, want, input)
+					}
+				}
+				for ; l < len(binding.HeaderParams); l++ {
+					got := binding.HeaderParams[l].FieldPath.String()
+					t.Errorf("svcs[%d].Methods[%d].Bindings[%d].HeaderParams[%d] = %q; want it to be missing; input = %v", i, j, k, l, got, input)
+				}
+				for ; l < len(wantBinding.HeaderParams); l++ {
+					want := wantBinding.HeaderParams[l].FieldPath.String()
+					t.Errorf("svcs[%d].Methods[%d].Bindings[%d].HeaderParams[%d] missing; want %q; input = %v", i, j, k, l, want, input)
+				}
+
+				for l = 0; l < len(binding.FieldBehaviors) && l < len(wantBinding.FieldBehaviors); l++ {
+					fb, wantFB := binding.FieldBehaviors[l], wantBinding.FieldBehaviors[l]
+					if got, want := fb.FieldPath.String(), wantFB.FieldPath.String(); got != want {
+						t.Errorf("svcs[%d].Methods[%d].Bindings[%d].FieldBehaviors[%d].FieldPath.String() = %q; want %q; input = %v", i, j, k, l, got, want, input)
+					}
+					if got, want := fb.Behaviors, wantFB.Behaviors; !reflect.DeepEqual(got, want) {
+						t.Errorf("svcs[%d].Methods[%d].Bindings[%d].FieldBehaviors[%d].Behaviors = %v; want %v; input = %v", i, j, k, l, got, want, input)
+					}
+				}
+				for ; l < len(binding.FieldBehaviors); l++ {
+					got := binding.FieldBehaviors[l].FieldPath.String()
+					t.Errorf("svcs[%d].Methods[%d].Bindings[%d].FieldBehaviors[%d] = %q; want it to be missing; input = %v", i, j, k, l, got, input)
+				}
+				for ; l < len(wantBinding.FieldBehaviors); l++ {
+					want := wantBinding.FieldBehaviors[l].FieldPath.String()
+					t.Errorf("svcs[%d].Methods[%d].Bindings[%d].FieldBehaviors[%d] missing; want %q; input = %v", i, j, k, l, want, input)
+				}
 			}
 			for ; k < len(meth.Bindings); k++ {
 				got := meth.Bindings[k]
@@ -125,6 +165,18 @@ func testExtractServices(t *testing.T, input []*descriptor.FileDescriptorProto,
 	}
 }
 
+// resourceReference returns the resource type "ref" refers to, prefixed with "parent of" if it refers to its parents.
+func resourceReference(ref *ResourceReference) string {
+	switch {
+	case ref == nil:
+		return ""
+	case ref.Parent:
+		return "parent of " + ref.Resource.GetType()
+	default:
+		return ref.Resource.GetType()
+	}
+}
+
 func crossLinkFixture(f *File) *File {
 	for _, m := range f.Messages {
 		m.File = f
@@ -477,6 +529,433 @@ func TestExtractServicesWithBodyPath(t *testing.T) {
 	testExtractServices(t, []*descriptor.FileDescriptorProto{&fd}, "path/to/example.proto", file.Services)
 }
 
+func TestExtractServicesWithHeaderParams(t *testing.T) {
+	src := `
+		name: "path/to/example.proto",
+		package: "example"
+		dependency: "options/options.proto"
+		message_type <
+			name: "EchoRequest"
+			field <
+				name: "id"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+			>
+			field <
+				name: "tenant"
+				number: 2
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+				options <
+					[gokitmux.binding] <
+						header: "X-Tenant-ID"
+						required: true
+					>
+				>
+			>
+			field <
+				name: "session"
+				number: 3
+				label: LABEL_OPTIONAL
+				type: TYPE_INT64
+				options <
+					[gokitmux.binding] <
+						cookie: "session"
+					>
+				>
+			>
+		>
+		service <
+			name: "ExampleService"
+			method <
+				name: "Echo"
+				input_type: "EchoRequest"
+				output_type: "EchoRequest"
+				options <
+					[google.api.http] <
+						get: "/v1/example/echo/{id}"
+					>
+				>
+			>
+		>
+	`
+	var fd descriptor.FileDescriptorProto
+	if err := proto.UnmarshalText(src, &fd); err != nil {
+		t.Fatalf("proto.UnmarshalText(%s, &fd) failed with %v; want success", src, err)
+	}
+	msg := &Message{
+		DescriptorProto: fd.MessageType[0],
+	}
+	for _, f := range fd.MessageType[0].Field {
+		msg.Fields = append(msg.Fields, &Field{FieldDescriptorProto: f})
+	}
+	file := &File{
+		FileDescriptorProto: &fd,
+		GoPkg: GoPackage{
+			Path: "path/to/example.pb",
+			Name: "example_pb",
+		},
+		Messages: []*Message{msg},
+		Services: []*Service{
+			{
+				ServiceDescriptorProto: fd.Service[0],
+				Methods: []*Method{
+					{
+						MethodDescriptorProto: fd.Service[0].Method[0],
+						RequestType:           msg,
+						ResponseType:          msg,
+						Bindings: []*Binding{
+							{
+								PathTmpl:   compilePath(t, "/v1/example/echo/{id}"),
+								HTTPMethod: "GET",
+								PathParams: []Parameter{
+									{
+										FieldPath: FieldPath{{Name: "id", Target: msg.Fields[0]}},
+										Target:    msg.Fields[0],
+									},
+								},
+								HeaderParams: []HeaderParameter{
+									{
+										Parameter: Parameter{
+											FieldPath: FieldPath{{Name: "tenant", Target: msg.Fields[1]}},
+											Target:    msg.Fields[1],
+										},
+										Header:   "X-Tenant-ID",
+										Required: true,
+									},
+									{
+										Parameter: Parameter{
+											FieldPath: FieldPath{{Name: "session", Target: msg.Fields[2]}},
+											Target:    msg.Fields[2],
+										},
+										Cookie: "session",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	crossLinkFixture(file)
+	testExtractServices(t, []*descriptor.FileDescriptorProto{&fd}, "path/to/example.proto", file.Services)
+}
+
+func TestExtractServicesWithFieldBehaviors(t *testing.T) {
+	src := `
+		name: "path/to/example.proto",
+		package: "example"
+		dependency: "google/api/field_behavior.proto"
+		message_type <
+			name: "Book"
+			field <
+				name: "title"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+				options <
+					[google.api.field_behavior]: REQUIRED
+				>
+			>
+			field <
+				name: "isbn"
+				number: 2
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+				options <
+					[google.api.field_behavior]: IMMUTABLE
+					[google.api.field_behavior]: OUTPUT_ONLY
+				>
+			>
+		>
+		message_type <
+			name: "CreateBookRequest"
+			field <
+				name: "book"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_MESSAGE
+				type_name: ".example.Book"
+				options <
+					[google.api.field_behavior]: REQUIRED
+				>
+			>
+			field <
+				name: "book_id"
+				number: 2
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+			>
+		>
+		service <
+			name: "ExampleService"
+			method <
+				name: "CreateBook"
+				input_type: "CreateBookRequest"
+				output_type: "Book"
+				options <
+					[google.api.http] <
+						post: "/v1/books"
+						body: "book"
+					>
+				>
+			>
+		>
+	`
+	var fd descriptor.FileDescriptorProto
+	if err := proto.UnmarshalText(src, &fd); err != nil {
+		t.Fatalf("proto.UnmarshalText(%s, &fd) failed with %v; want success", src, err)
+	}
+	book := &Message{
+		DescriptorProto: fd.MessageType[0],
+	}
+	for _, f := range fd.MessageType[0].Field {
+		book.Fields = append(book.Fields, &Field{FieldDescriptorProto: f})
+	}
+	req := &Message{
+		DescriptorProto: fd.MessageType[1],
+	}
+	for _, f := range fd.MessageType[1].Field {
+		req.Fields = append(req.Fields, &Field{FieldDescriptorProto: f})
+	}
+	file := &File{
+		FileDescriptorProto: &fd,
+		GoPkg: GoPackage{
+			Path: "path/to/example.pb",
+			Name: "example_pb",
+		},
+		Messages: []*Message{book, req},
+		Services: []*Service{
+			{
+				ServiceDescriptorProto: fd.Service[0],
+				Methods: []*Method{
+					{
+						MethodDescriptorProto: fd.Service[0].Method[0],
+						RequestType:           req,
+						ResponseType:          book,
+						Bindings: []*Binding{
+							{
+								PathTmpl:   compilePath(t, "/v1/books"),
+								HTTPMethod: "POST",
+								Body: &Body{
+									FieldPath: FieldPath{{Name: "book", Target: req.Fields[0]}},
+								},
+								FieldBehaviors: []FieldBehavior{
+									{
+										FieldPath: FieldPath{{Name: "book", Target: req.Fields[0]}},
+										Behaviors: []options.FieldBehavior{options.FieldBehavior_REQUIRED},
+									},
+									{
+										FieldPath: FieldPath{{Name: "book", Target: req.Fields[0]}, {Name: "title", Target: book.Fields[0]}},
+										Behaviors: []options.FieldBehavior{options.FieldBehavior_REQUIRED},
+									},
+									{
+										FieldPath: FieldPath{{Name: "book", Target: req.Fields[0]}, {Name: "isbn", Target: book.Fields[1]}},
+										Behaviors: []options.FieldBehavior{options.FieldBehavior_IMMUTABLE, options.FieldBehavior_OUTPUT_ONLY},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	crossLinkFixture(file)
+	testExtractServices(t, []*descriptor.FileDescriptorProto{&fd}, "path/to/example.proto", file.Services)
+}
+
+func TestFieldBehaviorHas(t *testing.T) {
+	fb := FieldBehavior{Behaviors: []options.FieldBehavior{options.FieldBehavior_IMMUTABLE, options.FieldBehavior_OUTPUT_ONLY}}
+	for _, spec := range []struct {
+		behavior options.FieldBehavior
+		want     bool
+	}{
+		{behavior: options.FieldBehavior_IMMUTABLE, want: true},
+		{behavior: options.FieldBehavior_OUTPUT_ONLY, want: true},
+		{behavior: options.FieldBehavior_REQUIRED, want: false},
+	} {
+		if got := fb.Has(spec.behavior); got != spec.want {
+			t.Errorf("fb.Has(%v) = %v; want %v", spec.behavior, got, spec.want)
+		}
+	}
+}
+
+func TestExtractServicesWithResourceReferences(t *testing.T) {
+	src := `
+		name: "path/to/example.proto",
+		package: "example"
+		dependency: "google/api/resource.proto"
+		message_type <
+			name: "Book"
+			field <
+				name: "name"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+			>
+			options <
+				[google.api.resource] <
+					type: "example.com/Book"
+					pattern: "publishers/{publisher}/books/{book}"
+					pattern: "books/{book}"
+				>
+			>
+		>
+		message_type <
+			name: "GetBookRequest"
+			field <
+				name: "name"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+				options <
+					[google.api.resource_reference] <
+						type: "example.com/Book"
+					>
+				>
+			>
+		>
+		message_type <
+			name: "ListBooksRequest"
+			field <
+				name: "parent"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_STRING
+				options <
+					[google.api.resource_reference] <
+						child_type: "example.com/Book"
+					>
+				>
+			>
+		>
+		message_type <
+			name: "UpdateBookRequest"
+			field <
+				name: "book"
+				number: 1
+				label: LABEL_OPTIONAL
+				type: TYPE_MESSAGE
+				type_name: ".example.Book"
+			>
+		>
+		service <
+			name: "ExampleService"
+			method <
+				name: "GetBook"
+				input_type: "GetBookRequest"
+				output_type: "Book"
+				options <
+					[google.api.http] <
+						get: "/v1/{name=publishers/*/books/*}"
+					>
+				>
+			>
+			method <
+				name: "ListBooks"
+				input_type: "ListBooksRequest"
+				output_type: "Book"
+				options <
+					[google.api.http] <
+						get: "/v1/{parent=publishers/*}/books"
+					>
+				>
+			>
+			method <
+				name: "UpdateBook"
+				input_type: "UpdateBookRequest"
+				output_type: "Book"
+				options <
+					[google.api.http] <
+						patch: "/v1/{book.name=publishers/*/books/*}"
+						body: "book"
+					>
+				>
+			>
+		>
+	`
+	var fd descriptor.FileDescriptorProto
+	if err := proto.UnmarshalText(src, &fd); err != nil {
+		t.Fatalf("proto.UnmarshalText(%s, &fd) failed with %v; want success", src, err)
+	}
+	var msgs []*Message
+	for _, md := range fd.MessageType {
+		msg := &Message{DescriptorProto: md}
+		for _, f := range md.Field {
+			msg.Fields = append(msg.Fields, &Field{FieldDescriptorProto: f})
+		}
+		msgs = append(msgs, msg)
+	}
+	book := &Resource{ResourceDescriptor: &options.ResourceDescriptor{Type: "example.com/Book"}, Message: msgs[0]}
+	binding := func(tmpl string, param Parameter) *Binding {
+		return &Binding{
+			PathTmpl:   compilePath(t, tmpl),
+			HTTPMethod: "GET",
+			PathParams: []Parameter{param},
+		}
+	}
+	update := binding("/v1/{book.name=publishers/*/books/*}", Parameter{
+		FieldPath: FieldPath{{Name: "book", Target: msgs[3].Fields[0]}, {Name: "name", Target: msgs[0].Fields[0]}},
+		Target:    msgs[0].Fields[0],
+		Resource:  &ResourceReference{Resource: book},
+	})
+	update.HTTPMethod = "PATCH"
+	update.Body = &Body{FieldPath: FieldPath{{Name: "book", Target: msgs[3].Fields[0]}}}
+	file := &File{
+		FileDescriptorProto: &fd,
+		GoPkg: GoPackage{
+			Path: "path/to/example.pb",
+			Name: "example_pb",
+		},
+		Messages: msgs,
+		Services: []*Service{
+			{
+				ServiceDescriptorProto: fd.Service[0],
+				Methods: []*Method{
+					{
+						MethodDescriptorProto: fd.Service[0].Method[0],
+						RequestType:           msgs[1],
+						ResponseType:          msgs[0],
+						Bindings: []*Binding{
+							binding("/v1/{name=publishers/*/books/*}", Parameter{
+								FieldPath: FieldPath{{Name: "name", Target: msgs[1].Fields[0]}},
+								Target:    msgs[1].Fields[0],
+								Resource:  &ResourceReference{Resource: book},
+							}),
+						},
+					},
+					{
+						MethodDescriptorProto: fd.Service[0].Method[1],
+						RequestType:           msgs[2],
+						ResponseType:          msgs[0],
+						Bindings: []*Binding{
+							binding("/v1/{parent=publishers/*}/books", Parameter{
+								FieldPath: FieldPath{{Name: "parent", Target: msgs[2].Fields[0]}},
+								Target:    msgs[2].Fields[0],
+								Resource:  &ResourceReference{Resource: book, Parent: true},
+							}),
+						},
+					},
+					{
+						MethodDescriptorProto: fd.Service[0].Method[2],
+						RequestType:           msgs[3],
+						ResponseType:          msgs[0],
+						Bindings:              []*Binding{update},
+					},
+				},
+			},
+		},
+	}
+
+	crossLinkFixture(file)
+	testExtractServices(t, []*descriptor.FileDescriptorProto{&fd}, "path/to/example.proto", file.Services)
+}
+
 func TestExtractServicesWithPathParam(t *testing.T) {
 	src := `
 		name: "path/to/example.proto",
@@ -961,6 +1440,80 @@ func TestExtractServicesWithError(t *testing.T) {
 				`,
 			},
 		},
+		// field bound to both the path and a header
+		{
+			target: "path/to/example.proto",
+			srcs: []string{`
+					name: "path/to/example.proto",
+					package: "example"
+					dependency: "options/options.proto"
+					message_type <
+						name: "StringMessage"
+						field <
+							name: "string"
+							number: 1
+							label: LABEL_OPTIONAL
+							type: TYPE_STRING
+							options <
+								[gokitmux.binding] <
+									header: "X-String"
+								>
+							>
+						>
+					>
+					service <
+						name: "ExampleService"
+						method <
+							name: "Echo"
+							input_type: "StringMessage"
+							output_type: "StringMessage"
+							options <
+								[google.api.http] <
+									get: "/v1/example/echo/{string}"
+								>
+							>
+						>
+					>
+				`,
+			},
+		},
+		// repeated field bound to a cookie
+		{
+			target: "path/to/example.proto",
+			srcs: []string{`
+					name: "path/to/example.proto",
+					package: "example"
+					dependency: "options/options.proto"
+					message_type <
+						name: "StringMessage"
+						field <
+							name: "string"
+							number: 1
+							label: LABEL_REPEATED
+							type: TYPE_STRING
+							options <
+								[gokitmux.binding] <
+									cookie: "string"
+								>
+							>
+						>
+					>
+					service <
+						name: "ExampleService"
+						method <
+							name: "Echo"
+							input_type: "StringMessage"
+							output_type: "StringMessage"
+							options <
+								[google.api.http] <
+									get: "/v1/example/echo"
+								>
+							>
+						>
+					>
+				`,
+			},
+		},
 	} {
 		reg := NewRegistry()
 
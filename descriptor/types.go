@@ -7,6 +7,9 @@ import (
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/thesoulless/protoc-gen-gokitmux/internal/casing"
 	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
+	options "google.golang.org/genproto/googleapis/api/annotations"
+
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
 )
 
 // IsWellKnownType returns true if the provided fully qualified type name is considered 'well-known'.
@@ -64,6 +67,8 @@ type Message struct {
 	Outers []string
 	*descriptor.DescriptorProto
 	Fields []*Field
+	// Resource is the resource declared by the google.api.resource option of this message, or nil.
+	Resource *Resource
 
 	// Index is proto path index of this message in File.
 	Index int
@@ -147,6 +152,8 @@ type Service struct {
 	*descriptor.ServiceDescriptorProto
 	// Methods is the list of methods defined in this service.
 	Methods []*Method
+	// CORS is the gokitmux.service_cors option of this service, or nil if it has none.
+	CORS *gmoptions.CORSPolicy
 }
 
 // FQSN returns the fully qualified service name of this service.
@@ -170,6 +177,10 @@ type Method struct {
 	// ResponseType is the message type of responses from this method.
 	ResponseType *Message
 	Bindings     []*Binding
+	// ResponseStatus is the gokitmux.status option of this method, or nil if it has none.
+	ResponseStatus *gmoptions.ResponseStatus
+	// CORS is the gokitmux.cors option of this method, or nil if it has none.
+	CORS *gmoptions.CORSPolicy
 }
 
 // FQMN returns a fully qualified rpc method name of this method.
@@ -192,6 +203,11 @@ type Binding struct {
 	HTTPMethod string
 	// PathParams is the list of parameters provided in HTTP request paths.
 	PathParams []Parameter
+	// HeaderParams is the list of parameters provided in HTTP request headers and cookies.
+	HeaderParams []HeaderParameter
+	// FieldBehaviors are the fields of the request, and of the messages within it,
+	// annotated with google.api.field_behavior.
+	FieldBehaviors []FieldBehavior
 	// Body describes parameters provided in HTTP request body.
 	Body *Body
 	// ResponseBody describes field in response struct to marshal in HTTP response body.
@@ -208,6 +224,9 @@ func (b *Binding) ExplicitParams() []string {
 	for _, p := range b.PathParams {
 		result = append(result, p.FieldPath.String())
 	}
+	for _, p := range b.HeaderParams {
+		result = append(result, p.FieldPath.String())
+	}
 	return result
 }
 
@@ -228,6 +247,91 @@ type Parameter struct {
 	Target *Field
 	// Method is the method which this parameter is used for.
 	Method *Method
+	// Resource is the resource type whose names the parameter holds, as referred to by the
+	// google.api.resource_reference of its field, or nil if it holds no resource names.
+	Resource *ResourceReference
+}
+
+// HeaderParameter is a parameter provided in an HTTP request header or cookie,
+// as set by the gokitmux.binding option of its field.
+type HeaderParameter struct {
+	Parameter
+	// Header is the name of the header the parameter is provided in. It is empty for cookies.
+	Header string
+	// Cookie is the name of the cookie the parameter is provided in. It is empty for headers.
+	Cookie string
+	// Required reports whether requests without the parameter are rejected.
+	Required bool
+}
+
+// FieldBehavior is a field of a request annotated with google.api.field_behavior.
+type FieldBehavior struct {
+	// FieldPath is the path to the field from the request.
+	FieldPath
+	// Behaviors are the behaviors the field is annotated with.
+	Behaviors []options.FieldBehavior
+}
+
+// Has returns true if the field is annotated with "behavior", otherwise false is returned.
+func (b FieldBehavior) Has(behavior options.FieldBehavior) bool {
+	for _, fb := range b.Behaviors {
+		if fb == behavior {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource is a resource type declared with the google.api.resource option of a message,
+// or the google.api.resource_definition option of a file.
+type Resource struct {
+	*options.ResourceDescriptor
+	// Message is the message the resource is declared by, or nil if it is declared by a file.
+	Message *Message
+}
+
+// NameField returns the name of the field of Message holding the names of the resource.
+func (r *Resource) NameField() string {
+	if f := r.GetNameField(); f != "" {
+		return f
+	}
+	return "name"
+}
+
+// ParentPatterns returns the patterns of the parents of the resource, i.e. its patterns
+// without their last collection and ID. Patterns of top-level resources have no parent.
+func (r *Resource) ParentPatterns() []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, pattern := range r.GetPattern() {
+		segments := strings.Split(pattern, "/")
+		if len(segments) <= 2 {
+			continue
+		}
+		parent := strings.Join(segments[:len(segments)-2], "/")
+		if !seen[parent] {
+			seen[parent] = true
+			result = append(result, parent)
+		}
+	}
+	return result
+}
+
+// ResourceReference is a reference of a field to the resource type whose names it holds.
+type ResourceReference struct {
+	// Resource is the resource type referred to.
+	Resource *Resource
+	// Parent reports whether the field holds the names of the parents of Resource,
+	// as referred to by the child_type of google.api.resource_reference.
+	Parent bool
+}
+
+// Patterns returns the patterns of the names the field holds.
+func (r *ResourceReference) Patterns() []string {
+	if r.Parent {
+		return r.Resource.ParentPatterns()
+	}
+	return r.Resource.GetPattern()
 }
 
 // ConvertFuncExpr returns a go expression of a converter function.
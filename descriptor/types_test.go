@@ -1,10 +1,12 @@
 package descriptor
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
 	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	options "google.golang.org/genproto/googleapis/api/annotations"
 )
 
 func TestGoPackageStandard(t *testing.T) {
@@ -204,3 +206,23 @@ func TestFieldPath(t *testing.T) {
 		t.Errorf("fpEmpty.AssignableExpr(%q) = %q; want %q", "resp", got, want)
 	}
 }
+
+func TestResourceParentPatterns(t *testing.T) {
+	res := &Resource{
+		ResourceDescriptor: &options.ResourceDescriptor{
+			Type: "example.com/Book",
+			Pattern: []string{
+				"publishers/{publisher}/books/{book}",
+				"publishers/{publisher}/shelves/{shelf}/books/{book}",
+				"books/{book}",
+			},
+		},
+	}
+	want := []string{"publishers/{publisher}", "publishers/{publisher}/shelves/{shelf}"}
+	if got := res.ParentPatterns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("res.ParentPatterns() = %q; want %q", got, want)
+	}
+	if got := (&ResourceReference{Resource: res, Parent: true}).Patterns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ResourceReference.Patterns() with Parent = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,193 @@
+// Package diff renders line based unified diffs between two texts.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a unified diff turning "a" into "b".
+// It returns an empty string if both texts are identical.
+func Unified(oldName, newName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := edits(splitLines(a), splitLines(b))
+
+	w := bytes.NewBuffer(nil)
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		h.write(w, ops)
+	}
+	return w.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits computes the shortest edit script between "a" and "b" with the Myers algorithm.
+// Only the diagonals -d..d+1 of "v" read when backtracking from step d are kept, so the trace
+// grows with the square of the number of edits instead of with the length of the texts.
+func edits(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	v := make([]int, 2*max+2)
+	// trace[d][d+k] is the furthest x reached on the diagonal k before step d.
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[max-d:max+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var ops []op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, op{opEqual, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{opInsert, b[y-1]})
+			} else {
+				ops = append(ops, op{opDelete, a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// hunk is a contiguous range of ops, including surrounding context.
+type hunk struct {
+	start, end int
+}
+
+func hunks(ops []op) []hunk {
+	var result []hunk
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		// extend the hunk until more than 2*context unchanged lines follow a change.
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != opEqual {
+				end = j
+				continue
+			}
+			if j-end > 2*context {
+				break
+			}
+		}
+		end += context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if n := len(result); n > 0 && result[n-1].end >= start {
+			result[n-1].end = end
+		} else {
+			result = append(result, hunk{start: start, end: end})
+		}
+		i = end - 1
+	}
+	return result
+}
+
+func (h hunk) write(w *bytes.Buffer, ops []op) {
+	// line numbers are 1-origin positions of the hunk in each text.
+	oldLine, newLine := 1, 1
+	for _, o := range ops[:h.start] {
+		if o.kind != opInsert {
+			oldLine++
+		}
+		if o.kind != opDelete {
+			newLine++
+		}
+	}
+	var oldCount, newCount int
+	for _, o := range ops[h.start:h.end] {
+		if o.kind != opInsert {
+			oldCount++
+		}
+		if o.kind != opDelete {
+			newCount++
+		}
+	}
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+	fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, o := range ops[h.start:h.end] {
+		w.WriteByte(byte(o.kind))
+		w.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			w.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
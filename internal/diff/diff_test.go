@@ -0,0 +1,120 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	for _, spec := range []struct {
+		a, b string
+		want string
+	}{
+		{
+			a:    "same\n",
+			b:    "same\n",
+			want: "",
+		},
+		{
+			a: "",
+			b: "a\nb\n",
+			want: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b: "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+			want: `--- old
++++ new
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			a: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b: "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			want: `--- old
++++ new
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,3 @@
+ 9
+ 10
+ 11
+-12
+`,
+		},
+		{
+			a: "a",
+			b: "b",
+			want: `--- old
++++ new
+@@ -1,1 +1,1 @@
+-a
+\ No newline at end of file
++b
+\ No newline at end of file
+`,
+		},
+	} {
+		if got, want := Unified("old", "new", spec.a, spec.b), spec.want; got != want {
+			t.Errorf("Unified(%q, %q) = \n%s; want\n%s", spec.a, spec.b, got, want)
+		}
+	}
+}
+
+func TestEditsLargeTexts(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20000; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		a = append(a, line)
+		switch i {
+		case 10, 10000:
+			b = append(b, "changed\n")
+		case 15000:
+		default:
+			b = append(b, line)
+		}
+	}
+	b = append(b, "appended\n")
+
+	ops := edits(a, b)
+	var oldLines, newLines []string
+	changes := 0
+	for _, o := range ops {
+		if o.kind != opInsert {
+			oldLines = append(oldLines, o.line)
+		}
+		if o.kind != opDelete {
+			newLines = append(newLines, o.line)
+		}
+		if o.kind != opEqual {
+			changes++
+		}
+	}
+	if got, want := strings.Join(oldLines, ""), strings.Join(a, ""); got != want {
+		t.Errorf("edits() does not keep the old text")
+	}
+	if got, want := strings.Join(newLines, ""), strings.Join(b, ""); got != want {
+		t.Errorf("edits() does not build the new text")
+	}
+	if want := 6; changes != want {
+		t.Errorf("edits() has %d changes; want %d", changes, want)
+	}
+}
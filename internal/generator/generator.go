@@ -2,15 +2,59 @@ package generator
 
 import (
 	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
 type Params struct {
+	// ModuleName is the go module the code is generated into.
+	// It is read from go.mod in the working directory if empty.
+	ModuleName         string
 	GenerateService    bool
 	MetricsPackage     string
 	ErrorEncoder       string
 	PackageName        string
 	RegisterFuncSuffix string
+	// GenerateTests generates httptest based tests of the routes.
+	GenerateTests bool
+	// GenerateFake generates a fake GatewayService into the fake package.
+	// It is implied by GenerateTests, as the tests use the fake.
+	GenerateFake bool
+	// RequireUnimplemented requires GatewayService implementations to embed UnimplementedGatewayService.
+	// It needs GenerateService.
+	RequireUnimplemented bool
+	// SplitServices generates an interface and route registration per proto service,
+	// GatewayService being their aggregate. It implies GenerateService.
+	SplitServices bool
+	// GRPCClient generates adapters implementing the services by forwarding to protoc-gen-go-grpc clients.
+	GRPCClient bool
+	// GRPCServer generates adapters implementing the services by calling protoc-gen-go-grpc servers in-process.
+	GRPCServer bool
+	// Router is the backend the routes are registered with: mux (default), servemux or chi.
+	Router string
+	// Transport is what the handlers are built with: gokit (default) or nethttp, without go-kit.
+	Transport string
+	// EmitUnpopulated, UseProtoNames, UseEnumNumbers and DiscardUnknown are the defaults of the
+	// options of the JSON marshaler, as in protojson. DiscardUnknown also applies to forms.
+	EmitUnpopulated bool
+	UseProtoNames   bool
+	UseEnumNumbers  bool
+	DiscardUnknown  bool
+	// Multipart decodes the multipart/form-data bodies with gen.DecodeMessage and gen.StreamMultipart.
+	Multipart bool
+	// Validate validates the decoded requests before calling the services.
+	Validate bool
+	// RejectImmutable rejects the update masks of partial updates masking IMMUTABLE fields of the body.
+	RejectImmutable bool
+	// NegotiateAccept rejects the requests whose Accept header no registered marshaler can answer
+	// before calling the services. The Encode methods must write the responses with gen.EncodeMessage.
+	NegotiateAccept bool
+	// SuccessStatus is how the status codes of the successful responses are selected: aip (default),
+	// inferring 201 Created and 204 No Content from the standard methods, or ok for 200 OK.
+	SuccessStatus string
+	// CORS is the CORS policy of the routes without a gokitmux.cors or gokitmux.service_cors option.
+	// Only the routes with an option answer cross-origin requests if it is nil.
+	CORS *gmoptions.CORSPolicy
 }
 
 // Generator is an abstraction of code generators.
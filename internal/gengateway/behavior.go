@@ -0,0 +1,69 @@
+package gengateway
+
+import (
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+// fieldBehaviors are the fields whose google.api.field_behavior the handler of a binding enforces.
+type fieldBehaviors struct {
+	// OutputOnly are the paths of the fields cleared from the requests.
+	OutputOnly []string
+	// Required are the paths of the fields requests are rejected without.
+	Required []string
+	// Immutable are the paths of the fields update masks are rejected with, relative to the body.
+	Immutable []string
+	// Unmasked are the paths of the OUTPUT_ONLY fields left out of the update masks filled from the body,
+	// relative to the body.
+	Unmasked []string
+}
+
+// bindingFieldBehaviors returns the field behaviors the handler of "b" enforces, or nil if there are none.
+// The fields within the body of bindings with an update mask are partially updated, so they are not
+// required, and their immutable fields cannot be masked if "rejectImmutable" is set.
+func bindingFieldBehaviors(b *descriptor.Binding, rejectImmutable bool) *fieldBehaviors {
+	mask := bindingUpdateMask(b)
+	var body string
+	if mask != nil {
+		body = b.Body.FieldPath.String() + "."
+	}
+	result := new(fieldBehaviors)
+	for _, fb := range b.FieldBehaviors {
+		path := fb.FieldPath.String()
+		inBody := mask != nil && strings.HasPrefix(path, body)
+		if fb.Has(annotations.FieldBehavior_OUTPUT_ONLY) {
+			result.OutputOnly = append(result.OutputOnly, path)
+			if inBody {
+				result.Unmasked = append(result.Unmasked, strings.TrimPrefix(path, body))
+			}
+		}
+		if fb.Has(annotations.FieldBehavior_REQUIRED) && !inBody {
+			result.Required = append(result.Required, path)
+		}
+		if rejectImmutable && fb.Has(annotations.FieldBehavior_IMMUTABLE) && inBody {
+			result.Immutable = append(result.Immutable, strings.TrimPrefix(path, body))
+		}
+	}
+	if len(result.OutputOnly) == 0 && len(result.Required) == 0 && len(result.Immutable) == 0 {
+		return nil
+	}
+	return result
+}
+
+// hasFieldBehaviors reports whether the handler of any binding of "services" enforces field behaviors,
+// see bindingFieldBehaviors.
+func hasFieldBehaviors(services []*descriptor.Service, rejectImmutable bool) bool {
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				if bindingFieldBehaviors(b, rejectImmutable) != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
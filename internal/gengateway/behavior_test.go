@@ -0,0 +1,99 @@
+package gengateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/genproto/googleapis/api/annotations"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+func TestBindingFieldBehaviors(t *testing.T) {
+	book := &descriptor.Field{
+		FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{
+			Name:     proto.String("book"),
+			Type:     protodescriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".example.Book"),
+		},
+	}
+	mask := &descriptor.Field{
+		FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{
+			Name:     proto.String("update_mask"),
+			Type:     protodescriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".google.protobuf.FieldMask"),
+		},
+	}
+	title := &descriptor.Field{FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{Name: proto.String("title")}}
+	isbn := &descriptor.Field{FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{Name: proto.String("isbn")}}
+	createTime := &descriptor.Field{FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{Name: proto.String("create_time")}}
+	behaviors := []descriptor.FieldBehavior{
+		{
+			FieldPath: descriptor.FieldPath{{Name: "book", Target: book}},
+			Behaviors: []annotations.FieldBehavior{annotations.FieldBehavior_REQUIRED},
+		},
+		{
+			FieldPath: descriptor.FieldPath{{Name: "book", Target: book}, {Name: "title", Target: title}},
+			Behaviors: []annotations.FieldBehavior{annotations.FieldBehavior_REQUIRED},
+		},
+		{
+			FieldPath: descriptor.FieldPath{{Name: "book", Target: book}, {Name: "isbn", Target: isbn}},
+			Behaviors: []annotations.FieldBehavior{annotations.FieldBehavior_IMMUTABLE},
+		},
+		{
+			FieldPath: descriptor.FieldPath{{Name: "book", Target: book}, {Name: "create_time", Target: createTime}},
+			Behaviors: []annotations.FieldBehavior{annotations.FieldBehavior_OUTPUT_ONLY},
+		},
+	}
+	method := &descriptor.Method{
+		RequestType: &descriptor.Message{Fields: []*descriptor.Field{book, mask}},
+	}
+
+	for _, spec := range []struct {
+		httpMethod      string
+		rejectImmutable bool
+		want            *fieldBehaviors
+	}{
+		{
+			httpMethod: "POST",
+			want: &fieldBehaviors{
+				OutputOnly: []string{"book.create_time"},
+				Required:   []string{"book", "book.title"},
+			},
+		},
+		{
+			httpMethod: "PATCH",
+			want: &fieldBehaviors{
+				OutputOnly: []string{"book.create_time"},
+				Required:   []string{"book"},
+				Unmasked:   []string{"create_time"},
+			},
+		},
+		{
+			httpMethod:      "PATCH",
+			rejectImmutable: true,
+			want: &fieldBehaviors{
+				OutputOnly: []string{"book.create_time"},
+				Required:   []string{"book"},
+				Immutable:  []string{"isbn"},
+				Unmasked:   []string{"create_time"},
+			},
+		},
+	} {
+		b := &descriptor.Binding{
+			Method:         method,
+			HTTPMethod:     spec.httpMethod,
+			Body:           &descriptor.Body{FieldPath: descriptor.FieldPath{{Name: "book", Target: book}}},
+			FieldBehaviors: behaviors,
+		}
+		if got := bindingFieldBehaviors(b, spec.rejectImmutable); !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("bindingFieldBehaviors(%s, %t) = %+v; want %+v", spec.httpMethod, spec.rejectImmutable, got, spec.want)
+		}
+	}
+
+	if got := bindingFieldBehaviors(&descriptor.Binding{Method: method, HTTPMethod: "GET"}, true); got != nil {
+		t.Errorf("bindingFieldBehaviors(GET without behaviors) = %+v; want nil", got)
+	}
+}
@@ -0,0 +1,54 @@
+package gengateway
+
+import (
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+// clientBinding is the binding the client endpoint of a method sends its requests with.
+type clientBinding struct {
+	// HTTPMethod is the HTTP method of the requests.
+	HTTPMethod string
+	// Path is the path template of the binding, e.g. "/v1/{name=shelves/*}".
+	Path string
+	// Body is the field path of the body, "*" for the whole request, or empty if the binding has none.
+	Body string
+	// Headers are the headers and cookies the fields bound to them are sent in.
+	Headers []clientHeader
+	// Bound are the field paths of the fields which are not sent in the query string, as they are in the
+	// path, the body, a header or a cookie.
+	Bound []string
+}
+
+// clientHeader is a header or a cookie a field is sent in.
+type clientHeader struct {
+	// Header is the name of the header. It is empty for cookies.
+	Header string
+	// Cookie is the name of the cookie. It is empty for headers.
+	Cookie string
+	// Field is the field path of the field.
+	Field string
+}
+
+// newClientBinding returns the binding of the client endpoint of "m": its first one.
+func newClientBinding(m *descriptor.Method) clientBinding {
+	b := m.Bindings[0]
+	c := clientBinding{
+		HTTPMethod: b.HTTPMethod,
+		Path:       b.PathTmpl.Template,
+	}
+	if b.Body != nil {
+		c.Body = b.Body.FieldPath.String()
+		if c.Body == "" {
+			c.Body = "*"
+		}
+	}
+	for _, p := range b.HeaderParams {
+		c.Headers = append(c.Headers, clientHeader{Header: p.Header, Cookie: p.Cookie, Field: p.FieldPath.String()})
+	}
+	for _, p := range b.ExplicitParams() {
+		if p != "" {
+			c.Bound = append(c.Bound, p)
+		}
+	}
+	return c
+}
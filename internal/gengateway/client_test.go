@@ -0,0 +1,77 @@
+package gengateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+func TestNewClientBinding(t *testing.T) {
+	path := func(names ...string) descriptor.FieldPath {
+		var result descriptor.FieldPath
+		for _, name := range names {
+			result = append(result, descriptor.FieldPathComponent{Name: name})
+		}
+		return result
+	}
+	for _, spec := range []struct {
+		binding *descriptor.Binding
+		want    clientBinding
+	}{
+		{
+			binding: &descriptor.Binding{
+				HTTPMethod: "GET",
+				PathTmpl:   httprule.Template{Template: "/v1/{name=books/*}"},
+				PathParams: []descriptor.Parameter{{FieldPath: path("name")}},
+			},
+			want: clientBinding{
+				HTTPMethod: "GET",
+				Path:       "/v1/{name=books/*}",
+				Bound:      []string{"name"},
+			},
+		},
+		{
+			binding: &descriptor.Binding{
+				HTTPMethod: "PATCH",
+				PathTmpl:   httprule.Template{Template: "/v1/{book.name=books/*}"},
+				PathParams: []descriptor.Parameter{{FieldPath: path("book", "name")}},
+				Body:       &descriptor.Body{FieldPath: path("book")},
+			},
+			want: clientBinding{
+				HTTPMethod: "PATCH",
+				Path:       "/v1/{book.name=books/*}",
+				Body:       "book",
+				Bound:      []string{"book", "book.name"},
+			},
+		},
+		{
+			binding: &descriptor.Binding{
+				HTTPMethod: "POST",
+				PathTmpl:   httprule.Template{Template: "/v1/greetings"},
+				HeaderParams: []descriptor.HeaderParameter{
+					{Parameter: descriptor.Parameter{FieldPath: path("tenant_id")}, Header: "X-Tenant-ID", Required: true},
+					{Parameter: descriptor.Parameter{FieldPath: path("session_id")}, Cookie: "session"},
+				},
+				Body: &descriptor.Body{},
+			},
+			want: clientBinding{
+				HTTPMethod: "POST",
+				Path:       "/v1/greetings",
+				Body:       "*",
+				Headers: []clientHeader{
+					{Header: "X-Tenant-ID", Field: "tenant_id"},
+					{Cookie: "session", Field: "session_id"},
+				},
+				Bound: []string{"tenant_id", "session_id"},
+			},
+		},
+	} {
+		m := &descriptor.Method{Bindings: []*descriptor.Binding{spec.binding}}
+		if got := newClientBinding(m); !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("newClientBinding(%s %s) = %+v; want %+v", spec.binding.HTTPMethod, spec.binding.PathTmpl.Template, got, spec.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gengateway
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
+)
+
+// corsService is a service with a gokitmux.service_cors option, whose policy is generated as a variable.
+type corsService struct {
+	// Name is the go name of the service.
+	Name string
+	// Policy is the CORSPolicy literal of its option.
+	Policy string
+}
+
+// corsRoute is a route whose policy is not the default one.
+type corsRoute struct {
+	// Key is the key of the route: its method and its path, as registered with the router backend.
+	Key string
+	// Policy is the expression of its policy: the variable of its service or the literal of its method.
+	Policy string
+}
+
+// corsParams are the CORS policies of the gateway.
+type corsParams struct {
+	// Default is the CORSPolicy literal of the parameters of the generator, or empty if there are none.
+	Default  string
+	Services []corsService
+	Routes   []corsRoute
+}
+
+// hasCORS reports whether the routes of "services" answer cross-origin requests, with the default
+// policy "p" of the parameters of the generator or the options of their services and methods.
+func hasCORS(p *gmoptions.CORSPolicy, services []*descriptor.Service) bool {
+	if p != nil {
+		return true
+	}
+	for _, svc := range services {
+		if svc.CORS != nil {
+			return true
+		}
+		for _, m := range svc.Methods {
+			if m.CORS != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newCORSParams returns the CORS policies of the routes of "services" registered with "router", the
+// default one being "p".
+func newCORSParams(router string, p *gmoptions.CORSPolicy, services []*descriptor.Service) (corsParams, error) {
+	var result corsParams
+	if p != nil {
+		result.Default = corsPolicyLiteral(p)
+	}
+	for _, svc := range services {
+		var svcPolicy string
+		if svc.CORS != nil {
+			svcPolicy = serviceName(svc) + "CORS"
+			result.Services = append(result.Services, corsService{Name: serviceName(svc), Policy: corsPolicyLiteral(svc.CORS)})
+		}
+		for _, m := range svc.Methods {
+			policy := svcPolicy
+			if m.CORS != nil {
+				policy = corsPolicyLiteral(m.CORS)
+			}
+			if policy == "" {
+				continue
+			}
+			for _, b := range m.Bindings {
+				path, err := routePath(router, b)
+				if err != nil {
+					return corsParams{}, err
+				}
+				result.Routes = append(result.Routes, corsRoute{Key: b.HTTPMethod + " " + path, Policy: policy})
+			}
+		}
+	}
+	return result, nil
+}
+
+// corsPolicyLiteral returns the CORSPolicy literal of "p".
+func corsPolicyLiteral(p *gmoptions.CORSPolicy) string {
+	var fields []string
+	for _, f := range []struct {
+		name   string
+		values []string
+	}{
+		{"AllowedOrigins", p.GetAllowedOrigins()},
+		{"AllowedHeaders", p.GetAllowedHeaders()},
+		{"ExposedHeaders", p.GetExposedHeaders()},
+	} {
+		if len(f.values) == 0 {
+			continue
+		}
+		quoted := make([]string, len(f.values))
+		for i, v := range f.values {
+			quoted[i] = fmt.Sprintf("%q", v)
+		}
+		fields = append(fields, fmt.Sprintf("%s: []string{%s}", f.name, strings.Join(quoted, ", ")))
+	}
+	if p.GetAllowCredentials() {
+		fields = append(fields, "AllowCredentials: true")
+	}
+	if p.GetMaxAge() != 0 {
+		fields = append(fields, fmt.Sprintf("MaxAge: %d", p.GetMaxAge()))
+	}
+	if len(fields) <= 1 {
+		return "&CORSPolicy{" + strings.Join(fields, "") + "}"
+	}
+	return "&CORSPolicy{\n" + strings.Join(fields, ",\n") + ",\n}"
+}
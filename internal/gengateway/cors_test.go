@@ -0,0 +1,160 @@
+package gengateway
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
+)
+
+func TestNewCORSParams(t *testing.T) {
+	binding := func(httpMethod, tmpl string) *descriptor.Binding {
+		compiler, err := httprule.Parse(tmpl)
+		if err != nil {
+			t.Fatalf("httprule.Parse(%q) failed with %v; want success", tmpl, err)
+		}
+		return &descriptor.Binding{HTTPMethod: httpMethod, PathTmpl: compiler.Compile()}
+	}
+	method := func(name string, cors *gmoptions.CORSPolicy, bindings ...*descriptor.Binding) *descriptor.Method {
+		m := &descriptor.Method{
+			MethodDescriptorProto: &protodescriptor.MethodDescriptorProto{Name: proto.String(name)},
+			Bindings:              bindings,
+			CORS:                  cors,
+		}
+		for _, b := range bindings {
+			b.Method = m
+		}
+		return m
+	}
+	library := &descriptor.Service{
+		ServiceDescriptorProto: &protodescriptor.ServiceDescriptorProto{Name: proto.String("Library")},
+		Methods: []*descriptor.Method{
+			method("GetBook", nil, binding("GET", "/v1/books/{book}")),
+			method("ListBooks", &gmoptions.CORSPolicy{AllowedOrigins: []string{"*"}}, binding("GET", "/v1/books")),
+		},
+		CORS: &gmoptions.CORSPolicy{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true},
+	}
+	greeter := &descriptor.Service{
+		ServiceDescriptorProto: &protodescriptor.ServiceDescriptorProto{Name: proto.String("Greeter")},
+		Methods: []*descriptor.Method{
+			method("SayHello", nil, binding("GET", "/v1/hello/{name}")),
+		},
+	}
+	services := []*descriptor.Service{library, greeter}
+
+	if !hasCORS(nil, services) {
+		t.Errorf("hasCORS(nil, %v) = false; want true", services)
+	}
+	if hasCORS(nil, []*descriptor.Service{greeter}) {
+		t.Errorf("hasCORS(nil, %v) = true; want false", greeter)
+	}
+
+	got, err := newCORSParams(routerServeMux, &gmoptions.CORSPolicy{AllowedOrigins: []string{"https://example.com"}}, services)
+	if err != nil {
+		t.Fatalf("newCORSParams() failed with %v; want success", err)
+	}
+	want := corsParams{
+		Default: `&CORSPolicy{AllowedOrigins: []string{"https://example.com"}}`,
+		Services: []corsService{
+			{Name: "LibraryService", Policy: "&CORSPolicy{\nAllowedOrigins: []string{\"https://example.com\"},\nAllowCredentials: true,\n}"},
+		},
+		Routes: []corsRoute{
+			{Key: "GET /v1/books/{book}", Policy: "LibraryServiceCORS"},
+			{Key: "GET /v1/books", Policy: `&CORSPolicy{AllowedOrigins: []string{"*"}}`},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newCORSParams() = %+v; want %+v", got, want)
+	}
+}
+
+// corsPolicyTest runs against the generated cors.gm.go, which only depends on the standard library.
+const corsPolicyTest = `package gen
+
+import (
+	"net/http"
+	"testing"
+)
+
+type Route struct {
+	Path    string
+	Handler http.Handler
+	Method  string
+	Name    string
+}
+
+func TestAllowOrigin(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		policy      *CORSPolicy
+		origin      string
+		allowed     bool
+		wantOrigin  string
+		credentials string
+	}{
+		{"listed origin", &CORSPolicy{AllowedOrigins: []string{"https://example.com"}}, "https://EXAMPLE.com", true, "https://EXAMPLE.com", ""},
+		{"other origin", &CORSPolicy{AllowedOrigins: []string{"https://example.com"}}, "https://other.com", false, "", ""},
+		{"any origin", &CORSPolicy{AllowedOrigins: []string{"*"}}, "https://other.com", true, "*", ""},
+		{"any origin with credentials", &CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "https://other.com", true, "https://other.com", "true"},
+		{"no policy", nil, "https://other.com", false, "", ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := http.Header{}
+			if got := c.policy.allowOrigin(h, c.origin); got != c.allowed {
+				t.Errorf("allowOrigin(%q) = %v; want %v", c.origin, got, c.allowed)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != c.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q; want %q", got, c.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != c.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q; want %q", got, c.credentials)
+			}
+		})
+	}
+}
+`
+
+// TestCORSPolicyAllowOrigin runs the generated CORSPolicy against the headers browsers accept.
+func TestCORSPolicyAllowOrigin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go test of the generated code in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not available")
+	}
+
+	code, err := applyCORSTemplate(params{PackageName: "gen"}, corsParams{})
+	if err != nil {
+		t.Fatalf("applyCORSTemplate() failed with %v; want success", err)
+	}
+	dir, err := ioutil.TempDir("", "cors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"go.mod":       "module gen\n\ngo 1.13\n",
+		"cors.gm.go":   code,
+		"cors_test.go": corsPolicyTest,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go test of the generated CORSPolicy failed with %v:\n%s", err, out)
+	}
+}
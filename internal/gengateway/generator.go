@@ -19,19 +19,58 @@ var (
 	errNoTargetService = errors.New("no target service defined in the file")
 )
 
+// Transports the handlers can be generated for.
+const (
+	// transportGoKit serves the methods with go-kit endpoints and httptransport servers.
+	transportGoKit = "gokit"
+	// transportNetHTTP serves the methods with plain net/http handlers, without go-kit.
+	transportNetHTTP = "nethttp"
+)
+
 type generator struct {
+	reg        *descriptor.Registry
 	modulePath string
 }
 
 // New returns a new generator which generates grpc gateway files.
-func New(modulePath string) gen.Generator {
+func New(reg *descriptor.Registry, modulePath string) gen.Generator {
 	return &generator{
+		reg:        reg,
 		modulePath: modulePath,
 	}
 }
 
 func (g *generator) Generate(targets []*descriptor.File, p gen.Params) ([]*plugin.CodeGeneratorResponse_File, error) {
 	var files []*pluginpb.CodeGeneratorResponse_File
+	if p.ModuleName == "" {
+		p.ModuleName = readModuleName()
+	}
+	if p.SplitServices {
+		p.GenerateService = true
+	}
+	if p.Router == "" {
+		p.Router = routerMux
+	}
+	if err := validRouter(p.Router); err != nil {
+		return nil, err
+	}
+	switch p.Transport {
+	case "":
+		p.Transport = transportGoKit
+	case transportGoKit, transportNetHTTP:
+	default:
+		return nil, fmt.Errorf("unknown transport %q, want %s or %s", p.Transport, transportGoKit, transportNetHTTP)
+	}
+	switch p.SuccessStatus {
+	case "":
+		p.SuccessStatus = successStatusAIP
+	case successStatusAIP, successStatusOK:
+	default:
+		return nil, fmt.Errorf("unknown success_status %q, want %s or %s", p.SuccessStatus, successStatusAIP, successStatusOK)
+	}
+	if p.RequireUnimplemented && !p.GenerateService {
+		return nil, errors.New("require_unimplemented needs gen_service, GatewayService is hand-written otherwise")
+	}
 
 	// Services
 	srvFiles, err := g.generateServices(targets, p)
@@ -41,7 +80,7 @@ func (g *generator) Generate(targets []*descriptor.File, p gen.Params) ([]*plugi
 	files = append(files, srvFiles...)
 
 	// Router
-	router, err := g.generateRouter(p)
+	router, err := g.generateRouter(targets, p)
 	if err != nil {
 		return nil, err
 	}
@@ -55,25 +94,186 @@ func (g *generator) Generate(targets []*descriptor.File, p gen.Params) ([]*plugi
 	files = append(files, muxkit)
 
 	// Endpoints
-	endpoints, err := g.generateEndpoints(p)
+	endpoints, err := g.generateEndpoints(targets, p)
 	if err != nil {
 		return nil, err
 	}
 	files = append(files, endpoints)
 
+	// GatewayService
+	if p.GenerateService {
+		service, err := g.generateGatewayService(targets, p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, service)
+	}
+
+	// Context
+	context, err := g.generateContext(p)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, context)
+
+	// Marshalers
+	marshal, err := g.generateMarshal(p)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, marshal)
+
+	// Metadata
+	metadata, err := g.generateMetadata(p)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, metadata)
+
+	// Params
+	if hasHeaderParams(boundServices(targets)) {
+		params, err := g.generateParams(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, params)
+	}
+
+	// Resource names
+	if hasResourceParams(boundServices(targets)) {
+		resources, err := g.generateResources(targets, p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, resources)
+	}
+
+	// CORS
+	if hasCORS(p.CORS, boundServices(targets)) {
+		cors, err := g.generateCORS(targets, p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, cors)
+	}
+
+	// Field behaviors
+	if hasFieldBehaviors(boundServices(targets), p.RejectImmutable) {
+		behavior, err := g.generateBehavior(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, behavior)
+	}
+
+	// Validation
+	if p.Validate {
+		validate, err := g.generateValidate(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, validate)
+	}
+
+	// Transport
+	if p.Transport == transportNetHTTP {
+		transport, err := g.generateTransport(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, transport)
+	} else {
+		client, err := g.generateClient(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, client)
+	}
+
+	// gRPC
+	if p.GRPCClient || p.GRPCServer {
+		grpc, err := g.generateGRPC(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, grpc)
+	}
+
+	// Unimplemented
+	unimplemented, err := g.generateUnimplemented(targets, p)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, unimplemented)
+
+	// Fake
+	if p.GenerateFake || p.GenerateTests {
+		fake, err := g.generateFake(targets, p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fake)
+	}
+
+	// Tests
+	if p.GenerateTests {
+		tests, err := g.generateTests(targets, p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, tests)
+	}
+
 	return files, nil
 }
 
+// file formats "code", the output of a template, into the generated file "name", relative to the module path.
+func (g *generator) file(name, code string) (*plugin.CodeGeneratorResponse_File, error) {
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		glog.Errorf("%v: %s", err, code)
+		return nil, err
+	}
+	output := g.modulePath + "/" + name
+	content := string(formatted)
+	return &plugin.CodeGeneratorResponse_File{
+		Name:    &output,
+		Content: &content,
+	}, nil
+}
+
 func (g *generator) generateService(file *descriptor.File, p gen.Params) (string, error) {
 	ps := param{
-		File:         file,
-		Metrics:      p.MetricsPackage,
-		ErrorEncoder: p.ErrorEncoder,
-		PackageName:  p.PackageName,
+		File:                 file,
+		Metrics:              p.MetricsPackage,
+		ErrorEncoder:         p.ErrorEncoder,
+		PackageName:          p.PackageName,
+		ModuleName:           p.ModuleName,
+		SplitServices:        p.SplitServices,
+		RequireUnimplemented: p.RequireUnimplemented,
+		Router:               p.Router,
+		Transport:            p.Transport,
+		Validate:             p.Validate,
+		RejectImmutable:      p.RejectImmutable,
+		NegotiateAccept:      p.NegotiateAccept,
+		SuccessStatus:        p.SuccessStatus,
 	}
 	return applyTemplate(ps)
 }
 
+func (g *generator) generateAdapters(file *descriptor.File, p gen.Params) (string, error) {
+	ps := param{
+		File:                 file,
+		PackageName:          p.PackageName,
+		ModuleName:           p.ModuleName,
+		SplitServices:        p.SplitServices,
+		RequireUnimplemented: p.RequireUnimplemented,
+		GRPCClient:           p.GRPCClient,
+		GRPCServer:           p.GRPCServer,
+	}
+	return applyAdaptersTemplate(ps)
+}
+
 func (g *generator) generateServices(files []*descriptor.File, p gen.Params) ([]*plugin.CodeGeneratorResponse_File, error) {
 	var outFiles []*plugin.CodeGeneratorResponse_File
 	for _, f := range files {
@@ -81,49 +281,48 @@ func (g *generator) generateServices(files []*descriptor.File, p gen.Params) ([]
 		if _err != nil {
 			return nil, _err
 		}
-		formatted, err := format.Source([]byte(code))
-		if err != nil {
-			glog.Errorf("%v: %s", err, code)
-			return nil, err
-		}
-		fmtStr := string(formatted)
 		fileNames := strings.Split(f.GetName(), "/")
 		name := fileNames[len(fileNames)-1]
 		ext := filepath.Ext(name)
 		name = strings.TrimSuffix(name, ext)
-		base := name + ".gm"
-		output := fmt.Sprintf("%s/%s/%s.go", g.modulePath, name, base)
-		outFiles = append(outFiles, &plugin.CodeGeneratorResponse_File{
-			Name:    &output,
-			Content: &fmtStr,
-		})
+		service, err := g.file(fmt.Sprintf("%s/%s.gm.go", name, name), code)
+		if err != nil {
+			return nil, err
+		}
+		outFiles = append(outFiles, service)
+
+		if !p.GRPCClient && !p.GRPCServer {
+			continue
+		}
+		code, err = g.generateAdapters(f, p)
+		if err != nil {
+			return nil, err
+		}
+		adapters, err := g.file(fmt.Sprintf("%s/%s_grpc.gm.go", name, name), code)
+		if err != nil {
+			return nil, err
+		}
+		outFiles = append(outFiles, adapters)
 	}
 
 	return outFiles, nil
 }
 
-func (g *generator) generateRouter(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+func (g *generator) generateRouter(files []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
 	ps := params{
-		Metrics:     p.MetricsPackage,
-		PackageName: p.PackageName,
+		Files:         files,
+		Metrics:       p.MetricsPackage,
+		PackageName:   p.PackageName,
+		SplitServices: p.SplitServices,
+		Router:        p.Router,
+		Transport:     p.Transport,
+		CORS:          hasCORS(p.CORS, boundServices(files)),
 	}
 	code, err := applyRoutesTemplate(ps)
 	if err != nil {
 		return nil, err
 	}
-
-	formatted, err := format.Source([]byte(code))
-	fmtStr := string(formatted)
-	if err != nil {
-		glog.Errorf("%v: %s", err, code)
-		panic(err)
-	}
-	base := g.modulePath + "/" + "routes.gm"
-	output := fmt.Sprintf("%s.go", base)
-	return &plugin.CodeGeneratorResponse_File{
-		Name:    &output,
-		Content: &fmtStr,
-	}, nil
+	return g.file("routes.gm.go", code)
 }
 
 func (g *generator) generateMuxkit(files []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
@@ -131,42 +330,218 @@ func (g *generator) generateMuxkit(files []*descriptor.File, p gen.Params) (*plu
 		Files:       files,
 		Metrics:     p.MetricsPackage,
 		PackageName: p.PackageName,
+		ModuleName:  p.ModuleName,
 	}
 	code, err := applyMuxkitTemplate(params)
 	if err != nil {
 		return nil, err
 	}
-	formatted, err := format.Source([]byte(code))
+	return g.file("muxkit/muxkit.gm.go", code)
+}
+
+func (g *generator) generateEndpoints(files []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		Files:         files,
+		Metrics:       p.MetricsPackage,
+		PackageName:   p.PackageName,
+		SplitServices: p.SplitServices,
+		Router:        p.Router,
+		Transport:     p.Transport,
+	}
+	code, err := applyEndpointsTemplate(params)
 	if err != nil {
 		return nil, err
 	}
-	fmtStr := string(formatted)
-	base := fmt.Sprintf("%s/%s/%s", g.modulePath, "muxkit", "muxkit.gm")
-	output := fmt.Sprintf("%s.go", base)
-	return &plugin.CodeGeneratorResponse_File{
-		Name:    &output,
-		Content: &fmtStr,
-	}, nil
+	return g.file("endpoints.gm.go", code)
 }
 
-func (g *generator) generateEndpoints(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+func (g *generator) generateGatewayService(files []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		Files:                files,
+		PackageName:          p.PackageName,
+		ModuleName:           p.ModuleName,
+		RequireUnimplemented: p.RequireUnimplemented,
+		SplitServices:        p.SplitServices,
+	}
+	code, err := applyServiceTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("service.gm.go", code)
+}
+
+func (g *generator) generateContext(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
 	params := params{
-		Metrics:     p.MetricsPackage,
 		PackageName: p.PackageName,
+		Multipart:   p.Multipart,
 	}
-	code, err := applyEndpointsTemplate(params)
+	code, err := applyContextTemplate(params)
 	if err != nil {
 		return nil, err
 	}
-	formatted, err := format.Source([]byte(code))
+	return g.file("context.gm.go", code)
+}
+
+func (g *generator) generateMarshal(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName:     p.PackageName,
+		EmitUnpopulated: p.EmitUnpopulated,
+		UseProtoNames:   p.UseProtoNames,
+		UseEnumNumbers:  p.UseEnumNumbers,
+		DiscardUnknown:  p.DiscardUnknown,
+		Multipart:       p.Multipart,
+	}
+	code, err := applyMarshalTemplate(params)
 	if err != nil {
 		return nil, err
 	}
-	fmtStr := string(formatted)
-	base := g.modulePath + "/" + "endpoints.gm"
-	output := fmt.Sprintf("%s.go", base)
-	return &plugin.CodeGeneratorResponse_File{
-		Name:    &output,
-		Content: &fmtStr,
-	}, nil
+	return g.file("marshal.gm.go", code)
+}
+
+func (g *generator) generateMetadata(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyMetadataTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("metadata.gm.go", code)
+}
+
+func (g *generator) generateResources(targets []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		Files:       targets,
+		PackageName: p.PackageName,
+	}
+	code, err := applyResourcesTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("resources.gm.go", code)
+}
+
+func (g *generator) generateCORS(targets []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	cors, err := newCORSParams(p.Router, p.CORS, boundServices(targets))
+	if err != nil {
+		return nil, err
+	}
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyCORSTemplate(params, cors)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("cors.gm.go", code)
+}
+
+func (g *generator) generateBehavior(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyBehaviorTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("behavior.gm.go", code)
+}
+
+func (g *generator) generateValidate(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyValidateTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("validate.gm.go", code)
+}
+
+func (g *generator) generateClient(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyClientTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("client.gm.go", code)
+}
+
+func (g *generator) generateParams(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyParamsTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("params.gm.go", code)
+}
+
+func (g *generator) generateTransport(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyTransportTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("transport.gm.go", code)
+}
+
+func (g *generator) generateGRPC(p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		PackageName: p.PackageName,
+	}
+	code, err := applyGRPCTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("grpc.gm.go", code)
+}
+
+func (g *generator) generateUnimplemented(files []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		Files:                files,
+		PackageName:          p.PackageName,
+		ModuleName:           p.ModuleName,
+		RequireUnimplemented: p.RequireUnimplemented,
+		SplitServices:        p.SplitServices,
+	}
+	code, err := applyUnimplementedTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("unimplemented.gm.go", code)
+}
+
+func (g *generator) generateFake(files []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		Files:       files,
+		PackageName: p.PackageName,
+		ModuleName:  p.ModuleName,
+	}
+	code, err := applyFakeTemplate(params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("fake/fake.gm.go", code)
+}
+
+func (g *generator) generateTests(files []*descriptor.File, p gen.Params) (*plugin.CodeGeneratorResponse_File, error) {
+	params := params{
+		Files:           files,
+		ErrorEncoder:    p.ErrorEncoder,
+		PackageName:     p.PackageName,
+		ModuleName:      p.ModuleName,
+		Validate:        p.Validate,
+		NegotiateAccept: p.NegotiateAccept,
+	}
+	code, err := applyTestsTemplate(g.reg, params)
+	if err != nil {
+		return nil, err
+	}
+	return g.file("routes.gm_test.go", code)
 }
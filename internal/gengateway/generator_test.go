@@ -0,0 +1,324 @@
+package gengateway
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	_ "google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	"github.com/thesoulless/protoc-gen-gokitmux/internal/diff"
+	gen "github.com/thesoulless/protoc-gen-gokitmux/internal/generator"
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+const (
+	// testModuleName is the go module the test corpus is generated into.
+	testModuleName = "example.com/gmtest"
+	// testModulePath is the directory within testModuleName the gateway is generated into.
+	testModulePath = "gen"
+)
+
+// generatorCase is a set of proto files from testdata/protos generated with a set of parameters.
+type generatorCase struct {
+	name   string
+	protos []string
+	params gen.Params
+	// companions are the directories in testdata/companions, besides common and the one named
+	// after the router, with the hand-written code the generated code needs.
+	companions []string
+}
+
+var generatorCases = []generatorCase{
+	{
+		name:       "default",
+		protos:     []string{"hello", "bye"},
+		companions: []string{"service", "handlers"},
+	},
+	{
+		name:   "metrics",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			MetricsPackage:  testModuleName + "/metrics",
+			ErrorEncoder:    "encodeError",
+			EmitUnpopulated: true,
+			UseProtoNames:   true,
+		},
+		companions: []string{"service", "handlers"},
+	},
+	{
+		name:   "tests",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			GenerateTests: true,
+		},
+		companions: []string{"service", "handlers"},
+	},
+	{
+		name:   "unimplemented",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			GenerateService:      true,
+			RequireUnimplemented: true,
+			GenerateTests:        true,
+		},
+		companions: []string{"handlers", "unimplemented"},
+	},
+	{
+		name:   "split",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			SplitServices:        true,
+			RequireUnimplemented: true,
+			GenerateTests:        true,
+		},
+		companions: []string{"split"},
+	},
+	{
+		name:   "grpc",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			SplitServices: true,
+			GRPCClient:    true,
+			GRPCServer:    true,
+		},
+		companions: []string{"split", "grpc"},
+	},
+	{
+		name:   "servemux",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			Router:        "servemux",
+			GenerateTests: true,
+			CORS:          &gmoptions.CORSPolicy{AllowedOrigins: []string{"*"}},
+		},
+		companions: []string{"service", "handlers"},
+	},
+	{
+		name:   "chi",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			Router:        "chi",
+			SplitServices: true,
+			CORS: &gmoptions.CORSPolicy{
+				AllowedOrigins: []string{"https://example.com"},
+				AllowedHeaders: []string{"Authorization"},
+			},
+		},
+		companions: []string{"split"},
+	},
+	{
+		name:   "nethttp",
+		protos: []string{"hello", "bye"},
+		params: gen.Params{
+			Transport:     "nethttp",
+			ErrorEncoder:  "encodeError",
+			GenerateTests: true,
+		},
+		companions: []string{"service", "nethttp"},
+	},
+	{
+		name:   "httpbody",
+		protos: []string{"files"},
+		params: gen.Params{
+			Router:          "chi",
+			GenerateService: true,
+			GenerateTests:   true,
+			Multipart:       true,
+		},
+		companions: []string{"httpbody"},
+	},
+	{
+		name:   "library",
+		protos: []string{"library"},
+		params: gen.Params{
+			GenerateService: true,
+			GenerateTests:   true,
+			Validate:        true,
+			RejectImmutable: true,
+			NegotiateAccept: true,
+			CORS:            &gmoptions.CORSPolicy{AllowedOrigins: []string{"https://example.com"}},
+		},
+		companions: []string{"library"},
+	},
+	{
+		name:   "mutable",
+		protos: []string{"library"},
+		params: gen.Params{
+			GenerateService: true,
+			GenerateTests:   true,
+		},
+		companions: []string{"library"},
+	},
+}
+
+// loadRequest builds a CodeGeneratorRequest for "protos", adding the files they depend on
+// from the global proto registry.
+func loadRequest(t *testing.T, protos []string) *plugin.CodeGeneratorRequest {
+	req := new(plugin.CodeGeneratorRequest)
+	seen := make(map[string]bool)
+	var addDeps func(deps []string)
+	addDeps = func(deps []string) {
+		for _, dep := range deps {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(dep)
+			if err != nil {
+				t.Fatalf("protoregistry.GlobalFiles.FindFileByPath(%q) failed with %v; want success", dep, err)
+			}
+			file := protodesc.ToFileDescriptorProto(fd)
+			addDeps(file.GetDependency())
+			req.ProtoFile = append(req.ProtoFile, file)
+		}
+	}
+
+	for _, name := range protos {
+		src, err := ioutil.ReadFile(filepath.Join("testdata", "protos", name+".prototxt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := new(descriptorpb.FileDescriptorProto)
+		if err := prototext.Unmarshal(src, file); err != nil {
+			t.Fatalf("prototext.Unmarshal(%s) failed with %v; want success", name, err)
+		}
+		addDeps(file.GetDependency())
+		seen[file.GetName()] = true
+		req.ProtoFile = append(req.ProtoFile, file)
+		req.FileToGenerate = append(req.FileToGenerate, file.GetName())
+	}
+	return req
+}
+
+// descriptorSet is the FileDescriptorSet of the hello and bye protos, and of the files they depend on,
+// which TestRunGenerate runs the generate command with. It is rewritten with -update.
+var descriptorSet = filepath.Join("testdata", "descriptor_set.pb")
+
+// TestDescriptorSet checks that descriptorSet holds the protos of testdata/protos it is written from.
+// The files they depend on are left as they were written.
+func TestDescriptorSet(t *testing.T) {
+	req := loadRequest(t, []string{"hello", "bye"})
+	if *update {
+		buf, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: req.ProtoFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(descriptorSet, buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	buf, err := ioutil.ReadFile(descriptorSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(buf, set); err != nil {
+		t.Fatalf("proto.Unmarshal(%s) failed with %v; want success", descriptorSet, err)
+	}
+	files := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, f := range set.GetFile() {
+		files[f.GetName()] = f
+	}
+	targets := make(map[string]bool)
+	for _, name := range req.FileToGenerate {
+		targets[name] = true
+	}
+	for _, f := range req.ProtoFile {
+		if targets[f.GetName()] && !proto.Equal(files[f.GetName()], f) {
+			t.Errorf("%s in %s differs from testdata/protos; run go test -update", f.GetName(), descriptorSet)
+		}
+	}
+}
+
+// generate runs the gateway generator against "c".
+func generate(t *testing.T, c generatorCase) []*plugin.CodeGeneratorResponse_File {
+	req := loadRequest(t, c.protos)
+	reg := descriptor.NewRegistry()
+	if err := reg.Load(req); err != nil {
+		t.Fatalf("reg.Load() failed with %v; want success", err)
+	}
+	var targets []*descriptor.File
+	for _, name := range req.FileToGenerate {
+		f, err := reg.LookupFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, f)
+	}
+
+	p := c.params
+	p.ModuleName = testModuleName
+	p.PackageName = testModulePath
+	files, err := New(reg, testModulePath).Generate(targets, p)
+	if err != nil {
+		t.Fatalf("Generate() failed with %v; want success", err)
+	}
+	return files
+}
+
+func TestGenerateGolden(t *testing.T) {
+	for _, c := range generatorCases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := filepath.Join("testdata", "golden", c.name)
+			if *update {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			generated := make(map[string]bool)
+			for _, f := range generate(t, c) {
+				golden := filepath.Join(dir, f.GetName()+".golden")
+				generated[golden] = true
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := ioutil.WriteFile(golden, []byte(f.GetContent()), 0644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				want, err := ioutil.ReadFile(golden)
+				if err != nil {
+					t.Errorf("%s is not in the golden files: %v; run go test -update", f.GetName(), err)
+					continue
+				}
+				if d := diff.Unified(golden, f.GetName(), string(want), f.GetContent()); d != "" {
+					t.Errorf("%s differs from the golden file; run go test -update if the change is expected:\n%s", f.GetName(), d)
+				}
+			}
+
+			err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				if !generated[path] && strings.HasSuffix(path, ".golden") {
+					t.Errorf("%s is no longer generated; run go test -update", path)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
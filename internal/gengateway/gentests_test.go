@@ -0,0 +1,110 @@
+//go:build gentests
+// +build gentests
+
+package gengateway
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generatedModule is the go.mod of the module the generated code is run in. The generator module is
+// replaced by the repository, for the options package the protos import. servemux needs go 1.22.
+const generatedModule = `module ` + testModuleName + `
+
+go 1.22
+
+require (
+	github.com/go-chi/chi/v5 v5.0.12
+	github.com/go-kit/kit v0.12.0
+	github.com/gorilla/mux v1.8.0
+	github.com/thesoulless/protoc-gen-gokitmux v0.0.0
+)
+
+replace github.com/thesoulless/protoc-gen-gokitmux => %s
+`
+
+// TestGeneratedCodeRuns writes the code generated for each generatorCase into a module of its own, with
+// the code protoc-gen-go generates for its protos and its companions, and runs go vet and the generated
+// tests on it. It needs the go command and the module proxy, so it only runs with the gentests build tag:
+//
+//	go test -tags gentests -run TestGeneratedCodeRuns ./internal/gengateway
+func TestGeneratedCodeRuns(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not available")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range generatorCases {
+		t.Run(c.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gmtest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			writeModule(t, dir, c, fmt.Sprintf(generatedModule, root))
+
+			for _, args := range [][]string{
+				{"mod", "tidy"},
+				{"vet", "./..."},
+				{"test", "./..."},
+			} {
+				cmd := exec.Command("go", args...)
+				cmd.Dir = dir
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("go %s failed with %v:\n%s", strings.Join(args, " "), err, out)
+				}
+			}
+		})
+	}
+}
+
+// writeModule writes the module of "c" into "dir", with "gomod" as its go.mod.
+func writeModule(t *testing.T, dir string, c generatorCase, gomod string) {
+	write := func(name, content string) {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("go.mod", gomod)
+	for _, f := range generatePB(t, c.protos) {
+		write(strings.TrimPrefix(f.GetName(), testModuleName+"/"), f.GetContent())
+	}
+	for _, f := range generate(t, c) {
+		write(f.GetName(), f.GetContent())
+	}
+	for _, d := range companionDirs(c) {
+		src := filepath.Join("testdata", "companions", d)
+		err := filepath.Walk(src, func(name string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(src, name)
+			if err != nil {
+				return err
+			}
+			content, err := ioutil.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			write(filepath.ToSlash(rel), string(content))
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gengateway
+
+import (
+	"fmt"
+	"strings"
+
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	"github.com/thesoulless/protoc-gen-gokitmux/internal/casing"
+)
+
+// headerParamConv is how the string value of a header or cookie is converted into a field.
+type headerParamConv struct {
+	// parse is the expression parsing the value "v" into "x" and "err". It is empty for strings.
+	parse string
+	// goType is the type "x" is converted to, if it is not the type of the field already.
+	goType string
+	// pkg is the package "parse" refers to.
+	pkg string
+}
+
+var headerParamConvs = map[protodescriptor.FieldDescriptorProto_Type]headerParamConv{
+	protodescriptor.FieldDescriptorProto_TYPE_STRING:   {},
+	protodescriptor.FieldDescriptorProto_TYPE_BYTES:    {parse: "base64.StdEncoding.DecodeString(v)", pkg: "encoding/base64"},
+	protodescriptor.FieldDescriptorProto_TYPE_BOOL:     {parse: "strconv.ParseBool(v)", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_DOUBLE:   {parse: "strconv.ParseFloat(v, 64)", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_FLOAT:    {parse: "strconv.ParseFloat(v, 32)", goType: "float32", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_INT64:    {parse: "strconv.ParseInt(v, 10, 64)", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_SINT64:   {parse: "strconv.ParseInt(v, 10, 64)", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_SFIXED64: {parse: "strconv.ParseInt(v, 10, 64)", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_INT32:    {parse: "strconv.ParseInt(v, 10, 32)", goType: "int32", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_SINT32:   {parse: "strconv.ParseInt(v, 10, 32)", goType: "int32", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_SFIXED32: {parse: "strconv.ParseInt(v, 10, 32)", goType: "int32", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_UINT64:   {parse: "strconv.ParseUint(v, 10, 64)", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_FIXED64:  {parse: "strconv.ParseUint(v, 10, 64)", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_UINT32:   {parse: "strconv.ParseUint(v, 10, 32)", goType: "uint32", pkg: "strconv"},
+	protodescriptor.FieldDescriptorProto_TYPE_FIXED32:  {parse: "strconv.ParseUint(v, 10, 32)", goType: "uint32", pkg: "strconv"},
+}
+
+// hasHeaderParams reports whether any binding of "services" has parameters in headers or cookies.
+func hasHeaderParams(services []*descriptor.Service) bool {
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				if len(b.HeaderParams) != 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// headerParamImports returns the packages the decoders of the header parameters of "services" use.
+func headerParamImports(services []*descriptor.Service) []descriptor.GoPackage {
+	seen := make(map[string]bool)
+	var result []descriptor.GoPackage
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				for _, p := range b.HeaderParams {
+					pkg := headerParamConvs[p.Target.GetType()].pkg
+					if pkg != "" && !seen[pkg] {
+						seen[pkg] = true
+						result = append(result, descriptor.GoPackage{Path: pkg})
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// headerParamDecoder returns the statements setting the field of "p" in the request "req"
+// from the HTTP request "r". They return a ParameterError of package "pkg" if the value
+// cannot be parsed or if it is required and missing.
+func headerParamDecoder(pkg string, p descriptor.HeaderParameter) (string, error) {
+	conv, ok := headerParamConvs[p.Target.GetType()]
+	if !ok {
+		return "", fmt.Errorf("unsupported field type %s of header parameter %s in %s.%s", p.Target.GetType(), p.FieldPath, p.Method.Service.GetName(), p.Method.GetName())
+	}
+	lookup, source := "HeaderValue", fmt.Sprintf("Header: %q", p.Header)
+	name := p.Header
+	if p.Cookie != "" {
+		lookup, source = "CookieValue", fmt.Sprintf("Cookie: %q", p.Cookie)
+		name = p.Cookie
+	}
+	// proto2 and proto3 optional fields are pointers, except for bytes.
+	pointer := (p.IsProto2() || p.Target.GetProto3Optional()) && p.Target.GetType() != protodescriptor.FieldDescriptorProto_TYPE_BYTES
+
+	var w strings.Builder
+	fmt.Fprintf(&w, "if v, ok := %s.%s(r, %q); ok {\n", pkg, lookup, name)
+	value := "v"
+	if conv.parse != "" {
+		fmt.Fprintf(&w, "x, err := %s\n", conv.parse)
+		fmt.Fprintf(&w, "if err != nil {\nreturn nil, &%s.ParameterError{%s, Err: err}\n}\n", pkg, source)
+		value = "x"
+		if conv.goType != "" {
+			value = conv.goType + "(x)"
+		}
+	}
+	field := "req." + casing.Camel(p.Target.GetName())
+	if pointer {
+		if value != "v" && value != "x" {
+			fmt.Fprintf(&w, "y := %s\n", value)
+			value = "y"
+		}
+		value = "&" + value
+	}
+	fmt.Fprintf(&w, "%s = %s\n", field, value)
+	if p.Required {
+		fmt.Fprintf(&w, "} else {\nreturn nil, &%s.ParameterError{%s}\n", pkg, source)
+	}
+	w.WriteString("}")
+	return w.String(), nil
+}
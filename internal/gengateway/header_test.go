@@ -0,0 +1,111 @@
+package gengateway
+
+import (
+	"go/format"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+func TestHeaderParamDecoder(t *testing.T) {
+	for _, spec := range []struct {
+		typ      protodescriptor.FieldDescriptorProto_Type
+		optional bool
+		header   string
+		cookie   string
+		required bool
+		want     string
+	}{
+		{
+			typ:      protodescriptor.FieldDescriptorProto_TYPE_STRING,
+			header:   "X-Tenant-ID",
+			required: true,
+			want: `if v, ok := gen.HeaderValue(r, "X-Tenant-ID"); ok {
+	req.Value = v
+} else {
+	return nil, &gen.ParameterError{Header: "X-Tenant-ID"}
+}`,
+		},
+		{
+			typ:      protodescriptor.FieldDescriptorProto_TYPE_STRING,
+			optional: true,
+			cookie:   "session",
+			want: `if v, ok := gen.CookieValue(r, "session"); ok {
+	req.Value = &v
+}`,
+		},
+		{
+			typ:    protodescriptor.FieldDescriptorProto_TYPE_INT32,
+			header: "X-Page-Size",
+			want: `if v, ok := gen.HeaderValue(r, "X-Page-Size"); ok {
+	x, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return nil, &gen.ParameterError{Header: "X-Page-Size", Err: err}
+	}
+	req.Value = int32(x)
+}`,
+		},
+		{
+			typ:      protodescriptor.FieldDescriptorProto_TYPE_UINT32,
+			optional: true,
+			cookie:   "version",
+			want: `if v, ok := gen.CookieValue(r, "version"); ok {
+	x, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return nil, &gen.ParameterError{Cookie: "version", Err: err}
+	}
+	y := uint32(x)
+	req.Value = &y
+}`,
+		},
+		{
+			typ:      protodescriptor.FieldDescriptorProto_TYPE_BYTES,
+			optional: true,
+			header:   "X-Token",
+			want: `if v, ok := gen.HeaderValue(r, "X-Token"); ok {
+	x, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, &gen.ParameterError{Header: "X-Token", Err: err}
+	}
+	req.Value = x
+}`,
+		},
+	} {
+		file := &descriptor.File{
+			FileDescriptorProto: &protodescriptor.FileDescriptorProto{Syntax: proto.String("proto3")},
+		}
+		field := &descriptor.Field{
+			Message: &descriptor.Message{File: file},
+			FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{
+				Name:           proto.String("value"),
+				Type:           spec.typ.Enum(),
+				Proto3Optional: proto.Bool(spec.optional),
+			},
+		}
+		p := descriptor.HeaderParameter{
+			Parameter: descriptor.Parameter{
+				FieldPath: descriptor.FieldPath{{Name: "value", Target: field}},
+				Target:    field,
+			},
+			Header:   spec.header,
+			Cookie:   spec.cookie,
+			Required: spec.required,
+		}
+		code, err := headerParamDecoder("gen", p)
+		if err != nil {
+			t.Errorf("headerParamDecoder(%v) failed with %v; want success", p, err)
+			continue
+		}
+		got, err := format.Source([]byte(code))
+		if err != nil {
+			t.Errorf("headerParamDecoder(%v) = %s, which does not parse: %v", p, code, err)
+			continue
+		}
+		if string(got) != spec.want {
+			t.Errorf("headerParamDecoder(%v) = %s; want %s", p, got, spec.want)
+		}
+	}
+}
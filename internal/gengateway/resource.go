@@ -0,0 +1,140 @@
+package gengateway
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	"github.com/thesoulless/protoc-gen-gokitmux/internal/casing"
+)
+
+// resource is a resource type whose names are held by path parameters, checked by the handlers
+// and parsed and formatted by the typed helpers generated for it.
+type resource struct {
+	// Type is the type of the resource, e.g. "library.googleapis.com/Book".
+	Type string
+	// Name is the go name of the resource, e.g. "Book".
+	Name string
+	// Patterns are the patterns of the names of the resource.
+	Patterns []string
+	// Variables are the variables of the patterns, in the order they first appear.
+	Variables []resourceVariable
+}
+
+// resourceVariable is a variable of the patterns of a resource, matching a single segment of its names.
+type resourceVariable struct {
+	// Name is the name of the variable, e.g. "publisher" in "publishers/{publisher}".
+	Name string
+	// Field is the go name of the field holding its ID in the typed names, e.g. "Publisher".
+	Field string
+}
+
+// resourceVariablePattern matches a segment of a pattern which is a variable.
+var resourceVariablePattern = regexp.MustCompile(`^\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// newResource returns the resource of "res". Its patterns must be made of literal segments and of
+// variables matching a single segment, e.g. "publishers/{publisher}/books/{book}".
+func newResource(res *descriptor.Resource) (*resource, error) {
+	typ := res.GetType()
+	result := &resource{
+		Type:     typ,
+		Name:     casing.Camel(typ[strings.LastIndex(typ, "/")+1:]),
+		Patterns: res.GetPattern(),
+	}
+	if len(result.Patterns) == 0 {
+		return nil, fmt.Errorf("resource %s has no pattern", typ)
+	}
+	seen := make(map[string]bool)
+	for _, pattern := range result.Patterns {
+		for _, segment := range strings.Split(pattern, "/") {
+			if segment == "" || (strings.ContainsAny(segment, "{}") && !resourceVariablePattern.MatchString(segment)) {
+				return nil, fmt.Errorf("pattern %q of resource %s: only literal segments and variables matching a single segment are supported", pattern, typ)
+			}
+			m := resourceVariablePattern.FindStringSubmatch(segment)
+			if m == nil || seen[m[1]] {
+				continue
+			}
+			seen[m[1]] = true
+			result.Variables = append(result.Variables, resourceVariable{Name: m[1], Field: casing.Camel(m[1])})
+		}
+	}
+	return result, nil
+}
+
+// hasResourceParams reports whether any binding of "services" has path parameters holding resource names.
+func hasResourceParams(services []*descriptor.Service) bool {
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				if bindingHasResourceParams(b) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// bindingHasResourceParams reports whether "b" has path parameters holding resource names.
+func bindingHasResourceParams(b *descriptor.Binding) bool {
+	for _, p := range b.PathParams {
+		if p.Resource != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// boundResources returns the resources whose names are held by the path parameters of "services",
+// sorted by their go names, which must be unique.
+func boundResources(services []*descriptor.Service) ([]*resource, error) {
+	byType := make(map[string]*resource)
+	byName := make(map[string]*resource)
+	var result []*resource
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				for _, p := range b.PathParams {
+					if p.Resource == nil || byType[p.Resource.Resource.GetType()] != nil {
+						continue
+					}
+					res, err := newResource(p.Resource.Resource)
+					if err != nil {
+						return nil, err
+					}
+					if other, ok := byName[res.Name]; ok {
+						return nil, fmt.Errorf("resources %s and %s have the same go name %s", other.Type, res.Type, res.Name)
+					}
+					byType[res.Type] = res
+					byName[res.Name] = res
+					result = append(result, res)
+				}
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// resourceNameCheck returns the statement returning a ResourceNameError of package "pkg" if the path
+// parameter "p" of the HTTP request "r" is not a name of its resource. It returns an empty string if "p"
+// holds no resource names.
+func resourceNameCheck(pkg string, p descriptor.Parameter) (string, error) {
+	if p.Resource == nil {
+		return "", nil
+	}
+	res, err := newResource(p.Resource.Resource)
+	if err != nil {
+		return "", err
+	}
+	expr := fmt.Sprintf("%s.%sResource", pkg, res.Name)
+	if p.Resource.Parent {
+		if len(p.Resource.Patterns()) == 0 {
+			return "", fmt.Errorf("path parameter %s refers to the parents of resource %s, which has none", p.FieldPath, res.Type)
+		}
+		expr += ".Parent()"
+	}
+	return fmt.Sprintf("if err := %s.CheckResourceName(r, %q, %s); err != nil {\n\treturn nil, err\n}", pkg, p.FieldPath.String(), expr), nil
+}
@@ -0,0 +1,99 @@
+package gengateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/genproto/googleapis/api/annotations"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+func TestNewResource(t *testing.T) {
+	res, err := newResource(&descriptor.Resource{
+		ResourceDescriptor: &annotations.ResourceDescriptor{
+			Type:    "library.example.com/Book",
+			Pattern: []string{"publishers/{publisher}/books/{book}", "books/{book}"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newResource() failed with %v; want success", err)
+	}
+	want := &resource{
+		Type:     "library.example.com/Book",
+		Name:     "Book",
+		Patterns: []string{"publishers/{publisher}/books/{book}", "books/{book}"},
+		Variables: []resourceVariable{
+			{Name: "publisher", Field: "Publisher"},
+			{Name: "book", Field: "Book"},
+		},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("newResource() = %+v; want %+v", res, want)
+	}
+
+	for _, pattern := range []string{"books/{book=**}", "books/{book}_{edition}", "books//{book}"} {
+		res := &descriptor.Resource{
+			ResourceDescriptor: &annotations.ResourceDescriptor{Type: "library.example.com/Book", Pattern: []string{pattern}},
+		}
+		if got, err := newResource(res); err == nil {
+			t.Errorf("newResource(%q) = %+v; want an error", pattern, got)
+		}
+	}
+}
+
+func TestResourceNameCheck(t *testing.T) {
+	book := &descriptor.Resource{
+		ResourceDescriptor: &annotations.ResourceDescriptor{
+			Type:    "library.example.com/Book",
+			Pattern: []string{"publishers/{publisher}/books/{book}"},
+		},
+	}
+	field := &descriptor.Field{FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{Name: proto.String("parent")}}
+	for _, spec := range []struct {
+		ref  *descriptor.ResourceReference
+		want string
+	}{
+		{
+			ref: &descriptor.ResourceReference{Resource: book},
+			want: `if err := gen.CheckResourceName(r, "parent", gen.BookResource); err != nil {
+	return nil, err
+}`,
+		},
+		{
+			ref: &descriptor.ResourceReference{Resource: book, Parent: true},
+			want: `if err := gen.CheckResourceName(r, "parent", gen.BookResource.Parent()); err != nil {
+	return nil, err
+}`,
+		},
+		{},
+	} {
+		p := descriptor.Parameter{
+			FieldPath: descriptor.FieldPath{{Name: "parent", Target: field}},
+			Target:    field,
+			Resource:  spec.ref,
+		}
+		got, err := resourceNameCheck("gen", p)
+		if err != nil {
+			t.Errorf("resourceNameCheck(%+v) failed with %v; want success", spec.ref, err)
+			continue
+		}
+		if got != spec.want {
+			t.Errorf("resourceNameCheck(%+v) = %s; want %s", spec.ref, got, spec.want)
+		}
+	}
+
+	top := &descriptor.Resource{
+		ResourceDescriptor: &annotations.ResourceDescriptor{Type: "library.example.com/Shelf", Pattern: []string{"shelves/{shelf}"}},
+	}
+	p := descriptor.Parameter{
+		FieldPath: descriptor.FieldPath{{Name: "parent", Target: field}},
+		Target:    field,
+		Resource:  &descriptor.ResourceReference{Resource: top, Parent: true},
+	}
+	if got, err := resourceNameCheck("gen", p); err == nil {
+		t.Errorf("resourceNameCheck(parent of %s) = %s; want an error", top.GetType(), got)
+	}
+}
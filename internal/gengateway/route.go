@@ -0,0 +1,207 @@
+package gengateway
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+// Router backends the routes can be registered with.
+const (
+	// routerMux is github.com/gorilla/mux.
+	routerMux = "mux"
+	// routerServeMux is the http.ServeMux of Go 1.22, with method and wildcard patterns.
+	routerServeMux = "servemux"
+	// routerChi is github.com/go-chi/chi/v5.
+	routerChi = "chi"
+)
+
+// routerImports are the packages imported by the routes of each backend.
+var routerImports = map[string][]descriptor.GoPackage{
+	routerMux:      {{Path: "net/http"}, {Path: "github.com/gorilla/mux"}},
+	routerServeMux: {{Path: "net/http"}},
+	routerChi:      {{Path: "net/http"}, {Path: "github.com/go-chi/chi/v5"}},
+}
+
+// routingImports are the packages imported by the routes of every backend, to answer the requests
+// they have no route for.
+var routingImports = []descriptor.GoPackage{
+	{Path: "strings"},
+	{Path: "google.golang.org/grpc/codes"},
+	{Path: "google.golang.org/grpc/status"},
+	{Path: "google.golang.org/protobuf/encoding/protojson"},
+}
+
+// standardMethods are the HTTP methods the methods allowed on a path are looked up among, besides the
+// ones of the bindings. OPTIONS is answered by the router itself.
+var standardMethods = []string{"DELETE", "GET", "HEAD", "PATCH", "POST", "PUT"}
+
+// routeMethods returns the sorted HTTP methods the methods allowed on a path are looked up among: the
+// standard ones and the ones of the bindings of "services", e.g. custom methods.
+func routeMethods(services []*descriptor.Service) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(method string) {
+		if method == "OPTIONS" || seen[method] {
+			return
+		}
+		seen[method] = true
+		result = append(result, method)
+	}
+	for _, method := range standardMethods {
+		add(method)
+	}
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				add(b.HTTPMethod)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// validRouter returns an error if "router" is not a supported backend.
+func validRouter(router string) error {
+	if _, ok := routerImports[router]; !ok {
+		return fmt.Errorf("unknown router %q, want one of %s, %s or %s", router, routerMux, routerServeMux, routerChi)
+	}
+	return nil
+}
+
+// pathSegment is a segment of a path template: a literal, a wildcard or a deep wildcard.
+type pathSegment struct {
+	literal  string
+	wildcard bool
+	deep     bool
+}
+
+// pathElement is a literal segment or a variable of a path template.
+type pathElement struct {
+	// variable is the field path of the variable. It is empty for literals.
+	variable string
+	segments []pathSegment
+}
+
+// routePath translates the path template of "b" into a pattern of "router".
+// Variables matching a single segment are supported by all the backends. Deep wildcards are
+// supported at the end of the template, and variables with literals or verbs following a variable
+// only by gorilla/mux.
+func routePath(router string, b *descriptor.Binding) (string, error) {
+	elems, err := pathElements(b.PathTmpl)
+	if err != nil {
+		return "", err
+	}
+	unsupported := func(what string) (string, error) {
+		return "", fmt.Errorf("%s of %s: %s are not supported by router %s", b.PathTmpl.Template, b.Method.GetName(), what, router)
+	}
+
+	var parts []string
+	for i, e := range elems {
+		if e.variable == "" {
+			parts = append(parts, e.segments[0].literal)
+			continue
+		}
+		last := i == len(elems)-1
+		switch {
+		case len(e.segments) == 1 && e.segments[0].wildcard:
+			if router == routerServeMux {
+				if last && b.PathTmpl.Verb != "" {
+					return unsupported("verbs following a variable")
+				}
+				parts = append(parts, "{"+pathValueName(e.variable)+"}")
+				continue
+			}
+			parts = append(parts, "{"+e.variable+"}")
+		case len(e.segments) == 1 && e.segments[0].deep && last && b.PathTmpl.Verb == "":
+			switch router {
+			case routerServeMux:
+				parts = append(parts, "{"+pathValueName(e.variable)+"...}")
+			case routerChi:
+				parts = append(parts, "*")
+			default:
+				parts = append(parts, "{"+e.variable+":.+}")
+			}
+		case router == routerMux:
+			var re []string
+			for _, s := range e.segments {
+				switch {
+				case s.deep:
+					re = append(re, ".+")
+				case s.wildcard:
+					re = append(re, "[^/]+")
+				default:
+					re = append(re, regexp.QuoteMeta(s.literal))
+				}
+			}
+			parts = append(parts, "{"+e.variable+":"+strings.Join(re, "/")+"}")
+		default:
+			return unsupported("variables matching multiple segments")
+		}
+	}
+
+	path := "/" + strings.Join(parts, "/")
+	if b.PathTmpl.Verb != "" {
+		path += ":" + b.PathTmpl.Verb
+	}
+	return path, nil
+}
+
+// routeWildcard returns the variable of "b" routed as the trailing "*" of its pattern of "router",
+// which chi does not name. It is empty if there is none.
+func routeWildcard(router string, b *descriptor.Binding) string {
+	if router != routerChi || b.PathTmpl.Verb != "" {
+		return ""
+	}
+	elems, err := pathElements(b.PathTmpl)
+	if err != nil || len(elems) == 0 {
+		return ""
+	}
+	last := elems[len(elems)-1]
+	if len(last.segments) != 1 || !last.segments[0].deep {
+		return ""
+	}
+	return last.variable
+}
+
+// pathElements evaluates the opcodes of "tmpl" into its literals and variables.
+func pathElements(tmpl httprule.Template) ([]pathElement, error) {
+	var stack []pathElement
+	for i := 0; i+1 < len(tmpl.OpCodes); i += 2 {
+		operand := tmpl.OpCodes[i+1]
+		switch utilities.OpCode(tmpl.OpCodes[i]) {
+		case utilities.OpLitPush:
+			stack = append(stack, pathElement{segments: []pathSegment{{literal: tmpl.Pool[operand]}}})
+		case utilities.OpPush:
+			stack = append(stack, pathElement{segments: []pathSegment{{wildcard: true}}})
+		case utilities.OpPushM:
+			stack = append(stack, pathElement{segments: []pathSegment{{deep: true}}})
+		case utilities.OpConcatN:
+			l := len(stack) - operand
+			var segments []pathSegment
+			for _, e := range stack[l:] {
+				segments = append(segments, e.segments...)
+			}
+			stack = append(stack[:l], pathElement{segments: segments})
+		case utilities.OpCapture:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("malformed path template %s", tmpl.Template)
+			}
+			stack[len(stack)-1].variable = tmpl.Pool[operand]
+		}
+	}
+	return stack, nil
+}
+
+// pathValueName returns the name of the http.ServeMux wildcard of the variable "fieldPath".
+// Wildcard names must be Go identifiers, so the dots of nested fields are replaced.
+func pathValueName(fieldPath string) string {
+	return strings.Replace(fieldPath, ".", "_", -1)
+}
@@ -0,0 +1,139 @@
+package gengateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/httprule"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+)
+
+func TestRoutePath(t *testing.T) {
+	for _, spec := range []struct {
+		tmpl string
+		// want maps the routers to the path expected, or to "" if an error is.
+		want map[string]string
+	}{
+		{
+			tmpl: "/v1/greetings",
+			want: map[string]string{
+				routerMux:      "/v1/greetings",
+				routerServeMux: "/v1/greetings",
+				routerChi:      "/v1/greetings",
+			},
+		},
+		{
+			tmpl: "/v1/{lang}/hello/{name=*}",
+			want: map[string]string{
+				routerMux:      "/v1/{lang}/hello/{name}",
+				routerServeMux: "/v1/{lang}/hello/{name}",
+				routerChi:      "/v1/{lang}/hello/{name}",
+			},
+		},
+		{
+			tmpl: "/v1/books/{book.id}",
+			want: map[string]string{
+				routerMux:      "/v1/books/{book.id}",
+				routerServeMux: "/v1/books/{book_id}",
+				routerChi:      "/v1/books/{book.id}",
+			},
+		},
+		{
+			tmpl: "/v1/files/{path=**}",
+			want: map[string]string{
+				routerMux:      "/v1/files/{path:.+}",
+				routerServeMux: "/v1/files/{path...}",
+				routerChi:      "/v1/files/*",
+			},
+		},
+		{
+			tmpl: "/v1/{name=shelves/*/books/*}",
+			want: map[string]string{
+				routerMux:      "/v1/{name:shelves/[^/]+/books/[^/]+}",
+				routerServeMux: "",
+				routerChi:      "",
+			},
+		},
+		{
+			tmpl: "/v1/operations/{name}:cancel",
+			want: map[string]string{
+				routerMux:      "/v1/operations/{name}:cancel",
+				routerServeMux: "",
+				routerChi:      "/v1/operations/{name}:cancel",
+			},
+		},
+		{
+			tmpl: "/v1/files/{path=**}:download",
+			want: map[string]string{
+				routerMux:      "/v1/files/{path:.+}:download",
+				routerServeMux: "",
+				routerChi:      "",
+			},
+		},
+	} {
+		compiler, err := httprule.Parse(spec.tmpl)
+		if err != nil {
+			t.Fatalf("httprule.Parse(%q) failed with %v; want success", spec.tmpl, err)
+		}
+		b := &descriptor.Binding{
+			Method: &descriptor.Method{
+				MethodDescriptorProto: &protodescriptor.MethodDescriptorProto{Name: proto.String("Example")},
+			},
+			PathTmpl: compiler.Compile(),
+		}
+		for router, want := range spec.want {
+			got, err := routePath(router, b)
+			if want == "" {
+				if err == nil {
+					t.Errorf("routePath(%q, %q) = %q; want an error", router, spec.tmpl, got)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("routePath(%q, %q) failed with %v; want success", router, spec.tmpl, err)
+				continue
+			}
+			if got != want {
+				t.Errorf("routePath(%q, %q) = %q; want %q", router, spec.tmpl, got, want)
+			}
+		}
+	}
+}
+
+func TestRouteWildcard(t *testing.T) {
+	for _, spec := range []struct {
+		router, tmpl, want string
+	}{
+		{routerChi, "/v1/files/{path=**}", "path"},
+		{routerChi, "/v1/{parent}/files/{file.path=**}", "file.path"},
+		{routerChi, "/v1/files/{path}", ""},
+		{routerChi, "/v1/operations/{name}:cancel", ""},
+		{routerMux, "/v1/files/{path=**}", ""},
+		{routerServeMux, "/v1/files/{path=**}", ""},
+	} {
+		compiler, err := httprule.Parse(spec.tmpl)
+		if err != nil {
+			t.Fatalf("httprule.Parse(%q) failed with %v; want success", spec.tmpl, err)
+		}
+		b := &descriptor.Binding{PathTmpl: compiler.Compile()}
+		if got := routeWildcard(spec.router, b); got != spec.want {
+			t.Errorf("routeWildcard(%q, %q) = %q; want %q", spec.router, spec.tmpl, got, spec.want)
+		}
+	}
+}
+
+func TestRouteMethods(t *testing.T) {
+	svc := &descriptor.Service{
+		Methods: []*descriptor.Method{
+			{Bindings: []*descriptor.Binding{{HTTPMethod: "GET"}, {HTTPMethod: "LIST"}}},
+			{Bindings: []*descriptor.Binding{{HTTPMethod: "OPTIONS"}, {HTTPMethod: "POST"}}},
+		},
+	}
+	want := []string{"DELETE", "GET", "HEAD", "LIST", "PATCH", "POST", "PUT"}
+	if got := routeMethods([]*descriptor.Service{svc}); !reflect.DeepEqual(got, want) {
+		t.Errorf("routeMethods() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,683 @@
+package gengateway
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/grpc-ecosystem/grpc-gateway/utilities"
+	"google.golang.org/genproto/googleapis/api/annotations"
+
+	gwdescriptor "github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	"github.com/thesoulless/protoc-gen-gokitmux/internal/casing"
+)
+
+const (
+	// maxSampleDepth limits how deep nested messages are populated in samples.
+	maxSampleDepth = 3
+
+	// httpBodyType is the message whose data is sent as the raw body of requests.
+	httpBodyType = ".google.api.HttpBody"
+	// httpBodySample is the data and the content type of the sampled bodies of google.api.HttpBody.
+	httpBodySample            = "value-data"
+	httpBodySampleContentType = "text/plain"
+
+	wildcardPlaceholder     = "\x00"
+	deepWildcardPlaceholder = "\x01"
+)
+
+// bindingSample is a HTTP request synthesized from a binding,
+// together with the request message the binding should extract from it.
+type bindingSample struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the path and query of the request.
+	URL string
+	// Body is the body of the request, JSON unless it is the data of a google.api.HttpBody.
+	// It is empty if the binding has no body.
+	Body string
+	// ContentType is the media type of Body.
+	ContentType string
+	// OutputOnlyBody is Body with OUTPUT_ONLY fields, which are left out of the update mask filled from it.
+	// It is empty if the body has no such fields.
+	OutputOnlyBody string
+	// Headers are the headers and cookies of the request bound to fields.
+	Headers []sampleHeader
+	// Want is a go expression of the request message expected to be extracted.
+	Want string
+	// Skip is the reason the binding cannot be sampled. It is empty if it can.
+	Skip string
+}
+
+// sampleHeader is a header or a cookie of a sampled request.
+type sampleHeader struct {
+	// Header is the name of the header. It is empty for cookies.
+	Header string
+	// Cookie is the name of the cookie. It is empty for headers.
+	Cookie string
+	// Value is the value of the header or the cookie.
+	Value string
+	// Required reports whether the request is rejected without it.
+	Required bool
+}
+
+// sampler synthesizes sample values of proto messages.
+type sampler struct {
+	reg *gwdescriptor.Registry
+	// goImports are the go packages of the types referenced in the samples.
+	goImports
+	// omit are the fields left out of the sample being synthesized, as their handler rejects or clears them.
+	omit map[*gwdescriptor.Field]bool
+}
+
+func newSampler(reg *gwdescriptor.Registry) *sampler {
+	return &sampler{
+		reg:       reg,
+		goImports: make(goImports),
+	}
+}
+
+// sampleMessage is a sampled value of a message.
+type sampleMessage struct {
+	msg    *gwdescriptor.Message
+	fields []*sampleField
+}
+
+// sampleField is a sampled value of a field.
+type sampleField struct {
+	field *gwdescriptor.Field
+	// text is the value as it appears in paths and query strings.
+	text string
+	// expr is the go expression of the value.
+	expr string
+	// json is the JSON representation of the value.
+	json string
+	// msg is the value of message typed fields.
+	msg *sampleMessage
+	// inBody reports whether the field is sent in the request body.
+	inBody bool
+}
+
+// lookup returns the sample of "f" within "m", creating an empty message sample
+// for message typed fields if it is not sampled yet.
+func (s *sampler) lookup(m *sampleMessage, f *gwdescriptor.Field) (*sampleField, error) {
+	for _, sf := range m.fields {
+		if sf.field == f {
+			return sf, nil
+		}
+	}
+	sf := &sampleField{field: f}
+	if f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		msg, err := s.reg.LookupMsg(m.msg.FQMN(), f.GetTypeName())
+		if err != nil {
+			return nil, err
+		}
+		sf.msg = &sampleMessage{msg: msg}
+	}
+	m.fields = append(m.fields, sf)
+	return sf, nil
+}
+
+// set sets the value at "path" within "m" to "value".
+func (s *sampler) set(m *sampleMessage, path gwdescriptor.FieldPath, value *sampleField) error {
+	for i, c := range path {
+		if i == len(path)-1 {
+			break
+		}
+		sf, err := s.lookup(m, c.Target)
+		if err != nil {
+			return err
+		}
+		if sf.msg == nil {
+			return fmt.Errorf("not an aggregate type: %s", c.Name)
+		}
+		m = sf.msg
+	}
+	for i, sf := range m.fields {
+		if sf.field == value.field {
+			m.fields[i] = value
+			return nil
+		}
+	}
+	m.fields = append(m.fields, value)
+	return nil
+}
+
+// scalar samples the value of the scalar field "f".
+// It returns nil if values of the field cannot be sampled.
+func (s *sampler) scalar(f *gwdescriptor.Field) *sampleField {
+	sf := &sampleField{field: f}
+	n := int(f.GetNumber())
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		sf.text = "value-" + strings.Replace(f.GetName(), "_", "-", -1)
+		sf.expr = strconv.Quote(sf.text)
+		sf.json = sf.expr
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		v := "value-" + f.GetName()
+		sf.expr = fmt.Sprintf("[]byte(%q)", v)
+		sf.json = strconv.Quote(base64.StdEncoding.EncodeToString([]byte(v)))
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		sf.text = "true"
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		sf.text = fmt.Sprintf("%d.5", n)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		e, err := s.reg.LookupEnum(f.Message.FQMN(), f.GetTypeName())
+		if err != nil || len(e.GetValue()) < 2 {
+			return nil
+		}
+		s.add(e.File.GoPkg)
+		sf.text = strconv.Itoa(int(e.GetValue()[1].GetNumber()))
+		sf.expr = fmt.Sprintf("%s(%s)", e.GoType(""), sf.text)
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return nil
+	default:
+		sf.text = strconv.Itoa(n)
+	}
+	if sf.expr == "" {
+		sf.expr = sf.text
+	}
+	if sf.json == "" {
+		sf.json = sf.text
+	}
+	return s.pointer(sf)
+}
+
+// text samples the scalar field "f" with the value "text", e.g. from a path template.
+func (s *sampler) text(f *gwdescriptor.Field, text string) (*sampleField, error) {
+	sf := s.scalar(f)
+	if sf == nil || sf.text == "" {
+		return nil, fmt.Errorf("field %s of type %s cannot be sampled in a path", f.GetName(), f.GetType())
+	}
+	if sf.text == text {
+		return sf, nil
+	}
+	if f.GetType() != descriptor.FieldDescriptorProto_TYPE_STRING {
+		return nil, fmt.Errorf("field %s of type %s cannot hold %q", f.GetName(), f.GetType(), text)
+	}
+	sf = &sampleField{field: f, text: text, expr: strconv.Quote(text), json: strconv.Quote(text)}
+	return s.pointer(sf), nil
+}
+
+// pointer wraps the go expression of "sf" into a pointer if the field has explicit presence.
+func (s *sampler) pointer(sf *sampleField) *sampleField {
+	f := sf.field
+	proto2 := f.Message.File.Syntax == nil || f.Message.File.GetSyntax() == "proto2"
+	if !(proto2 || f.GetProto3Optional()) || f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return sf
+	}
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		sf.expr = sf.expr + ".Enum()"
+	default:
+		sf.expr = fmt.Sprintf("proto.%s(%s)", pointerFuncs[f.GetType()], sf.expr)
+	}
+	return sf
+}
+
+var pointerFuncs = map[descriptor.FieldDescriptorProto_Type]string{
+	descriptor.FieldDescriptorProto_TYPE_DOUBLE:   "Float64",
+	descriptor.FieldDescriptorProto_TYPE_FLOAT:    "Float32",
+	descriptor.FieldDescriptorProto_TYPE_INT64:    "Int64",
+	descriptor.FieldDescriptorProto_TYPE_UINT64:   "Uint64",
+	descriptor.FieldDescriptorProto_TYPE_INT32:    "Int32",
+	descriptor.FieldDescriptorProto_TYPE_FIXED64:  "Uint64",
+	descriptor.FieldDescriptorProto_TYPE_FIXED32:  "Uint32",
+	descriptor.FieldDescriptorProto_TYPE_BOOL:     "Bool",
+	descriptor.FieldDescriptorProto_TYPE_STRING:   "String",
+	descriptor.FieldDescriptorProto_TYPE_UINT32:   "Uint32",
+	descriptor.FieldDescriptorProto_TYPE_SFIXED32: "Int32",
+	descriptor.FieldDescriptorProto_TYPE_SFIXED64: "Int64",
+	descriptor.FieldDescriptorProto_TYPE_SINT32:   "Int32",
+	descriptor.FieldDescriptorProto_TYPE_SINT64:   "Int64",
+}
+
+// sampleable reports whether values of "f" are synthesized in bodies and query strings.
+// Oneofs, maps and well-known types are left out, since their JSON representation depends on the marshaler.
+func (s *sampler) sampleable(f *gwdescriptor.Field) bool {
+	if s.omit[f] || (f.OneofIndex != nil && !f.GetProto3Optional()) {
+		return false
+	}
+	if f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		if gwdescriptor.IsWellKnownType(f.GetTypeName()) || strings.HasPrefix(f.GetTypeName(), ".google.protobuf.") {
+			return false
+		}
+		msg, err := s.reg.LookupMsg(f.Message.FQMN(), f.GetTypeName())
+		if err != nil || msg.GetOptions().GetMapEntry() {
+			return false
+		}
+	}
+	return true
+}
+
+// fill samples the fields of "m" which are not sampled yet and whose name is not in "exclude".
+func (s *sampler) fill(m *sampleMessage, exclude map[string]bool, depth int) error {
+	for _, f := range m.msg.Fields {
+		if exclude[f.GetName()] || !s.sampleable(f) {
+			continue
+		}
+		if f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			sf := s.scalar(f)
+			if sf == nil {
+				continue
+			}
+			sf.inBody = true
+			if err := s.set(m, nil, sf); err != nil {
+				return err
+			}
+			continue
+		}
+		if depth >= maxSampleDepth {
+			continue
+		}
+		sf, err := s.lookup(m, f)
+		if err != nil {
+			return err
+		}
+		sf.inBody = true
+		if err := s.fill(sf.msg, nil, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goExpr returns the go expression of "m".
+func (s *sampler) goExpr(m *sampleMessage) string {
+	var fields []string
+	for _, sf := range m.fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", casing.Camel(sf.field.GetName()), s.fieldExpr(sf)))
+	}
+	return fmt.Sprintf("&%s{%s}", s.goType(m.msg), strings.Join(fields, ", "))
+}
+
+func (s *sampler) fieldExpr(sf *sampleField) string {
+	expr := sf.expr
+	elem := ""
+	if sf.msg != nil {
+		expr = s.goExpr(sf.msg)
+		elem = "*" + s.goType(sf.msg.msg)
+	}
+	if sf.field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return expr
+	}
+	if sf.msg == nil {
+		// the element type of the slice is inferred from the element expression.
+		elem = strings.TrimPrefix(goScalarTypes[sf.field.GetType()], "*")
+		if sf.field.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM {
+			elem = strings.SplitN(sf.expr, "(", 2)[0]
+		}
+	}
+	return fmt.Sprintf("[]%s{%s}", elem, expr)
+}
+
+var goScalarTypes = map[descriptor.FieldDescriptorProto_Type]string{
+	descriptor.FieldDescriptorProto_TYPE_DOUBLE:   "float64",
+	descriptor.FieldDescriptorProto_TYPE_FLOAT:    "float32",
+	descriptor.FieldDescriptorProto_TYPE_INT64:    "int64",
+	descriptor.FieldDescriptorProto_TYPE_UINT64:   "uint64",
+	descriptor.FieldDescriptorProto_TYPE_INT32:    "int32",
+	descriptor.FieldDescriptorProto_TYPE_FIXED64:  "uint64",
+	descriptor.FieldDescriptorProto_TYPE_FIXED32:  "uint32",
+	descriptor.FieldDescriptorProto_TYPE_BOOL:     "bool",
+	descriptor.FieldDescriptorProto_TYPE_STRING:   "string",
+	descriptor.FieldDescriptorProto_TYPE_BYTES:    "[]byte",
+	descriptor.FieldDescriptorProto_TYPE_UINT32:   "uint32",
+	descriptor.FieldDescriptorProto_TYPE_SFIXED32: "int32",
+	descriptor.FieldDescriptorProto_TYPE_SFIXED64: "int64",
+	descriptor.FieldDescriptorProto_TYPE_SINT32:   "int32",
+	descriptor.FieldDescriptorProto_TYPE_SINT64:   "int64",
+}
+
+// bodyJSON returns the JSON representation of the fields of "m" sent in the body.
+// Fields are named after their proto names, which both protojson and encoding/json accept.
+func (s *sampler) bodyJSON(m *sampleMessage) string {
+	var fields []string
+	for _, sf := range m.fields {
+		if !sf.inBody {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%q:%s", sf.field.GetName(), s.fieldJSON(sf)))
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+func (s *sampler) fieldJSON(sf *sampleField) string {
+	v := sf.json
+	if sf.msg != nil {
+		v = s.bodyJSON(sf.msg)
+	}
+	if sf.field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return "[" + v + "]"
+	}
+	return v
+}
+
+// httpBody samples the google.api.HttpBody "msg", sent as a raw body of httpBodySample.
+func (s *sampler) httpBody(msg *gwdescriptor.Message) *sampleMessage {
+	m := &sampleMessage{msg: msg}
+	for _, f := range msg.Fields {
+		switch f.GetName() {
+		case "content_type":
+			m.fields = append(m.fields, &sampleField{field: f, expr: strconv.Quote(httpBodySampleContentType)})
+		case "data":
+			m.fields = append(m.fields, &sampleField{field: f, expr: fmt.Sprintf("[]byte(%q)", httpBodySample)})
+		}
+	}
+	return m
+}
+
+// markBody marks "sf" and all the fields within it as sent in the body.
+func markBody(sf *sampleField) {
+	sf.inBody = true
+	if sf.msg == nil {
+		return
+	}
+	for _, f := range sf.msg.fields {
+		markBody(f)
+	}
+}
+
+// sample synthesizes a request for "b".
+func (s *sampler) sample(b *gwdescriptor.Binding) (*bindingSample, error) {
+	result := &bindingSample{Method: b.HTTPMethod}
+	skip := func(format string, args ...interface{}) (*bindingSample, error) {
+		result.Skip = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+	root := &sampleMessage{msg: b.Method.RequestType}
+
+	// output only fields are cleared, and immutable ones cannot be updated.
+	s.omit = make(map[*gwdescriptor.Field]bool)
+	mask := bindingUpdateMask(b)
+	for _, fb := range b.FieldBehaviors {
+		if fb.Has(annotations.FieldBehavior_OUTPUT_ONLY) || (mask != nil && fb.Has(annotations.FieldBehavior_IMMUTABLE)) {
+			s.omit[fb.FieldPath[len(fb.FieldPath)-1].Target] = true
+		}
+	}
+
+	// bound is the set of top-level fields bound by the path and the body.
+	bound := make(map[string]bool)
+	params := make(map[string]gwdescriptor.Parameter)
+	for _, p := range b.PathParams {
+		params[p.FieldPath.String()] = p
+		bound[p.FieldPath[0].Name] = true
+	}
+
+	// evaluate the path template, capturing sampled values of its variables.
+	tmpl := b.PathTmpl
+	var stack []string
+	for i := 0; i+1 < len(tmpl.OpCodes); i += 2 {
+		operand := tmpl.OpCodes[i+1]
+		switch utilities.OpCode(tmpl.OpCodes[i]) {
+		case utilities.OpLitPush:
+			stack = append(stack, tmpl.Pool[operand])
+		case utilities.OpPush:
+			stack = append(stack, wildcardPlaceholder)
+		case utilities.OpPushM:
+			stack = append(stack, deepWildcardPlaceholder)
+		case utilities.OpConcatN:
+			l := len(stack) - operand
+			stack = append(stack[:l], strings.Join(stack[l:], "/"))
+		case utilities.OpCapture:
+			value := stack[len(stack)-1]
+			p, ok := params[tmpl.Pool[operand]]
+			if !ok {
+				return nil, fmt.Errorf("no parameter for %s in %s", tmpl.Pool[operand], tmpl.Template)
+			}
+			if p.Target.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+				return skip("path parameter %s of type %s is not synthesized", p.FieldPath, p.Target.GetTypeName())
+			}
+			var sf *sampleField
+			if value == wildcardPlaceholder {
+				sf = s.scalar(p.Target)
+				if sf == nil || sf.text == "" {
+					return skip("path parameter %s of type %s is not synthesized", p.FieldPath, p.Target.GetType())
+				}
+			} else {
+				var err error
+				if sf, err = s.text(p.Target, fillPlaceholders(value)); err != nil {
+					return skip("%v", err)
+				}
+			}
+			if err := s.set(root, p.FieldPath, sf); err != nil {
+				return nil, err
+			}
+			stack[len(stack)-1] = sf.text
+		}
+	}
+	result.URL = "/" + fillPlaceholders(strings.Join(stack, "/"))
+	if tmpl.Verb != "" {
+		result.URL += ":" + tmpl.Verb
+	}
+
+	for _, p := range b.HeaderParams {
+		sf := s.scalar(p.Target)
+		if sf == nil {
+			return skip("header parameter %s of type %s is not synthesized", p.FieldPath, p.Target.GetType())
+		}
+		value := sf.text
+		if p.Target.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES {
+			// the value of bytes is base64 encoded, as in JSON.
+			value, _ = strconv.Unquote(sf.json)
+		}
+		if err := s.set(root, p.FieldPath, sf); err != nil {
+			return nil, err
+		}
+		bound[p.FieldPath[0].Name] = true
+		result.Headers = append(result.Headers, sampleHeader{
+			Header:   p.Header,
+			Cookie:   p.Cookie,
+			Value:    value,
+			Required: p.Required,
+		})
+	}
+
+	switch {
+	case b.Body == nil:
+	case len(b.Body.FieldPath) == 0 && root.msg.FQMN() == httpBodyType:
+		root = s.httpBody(root.msg)
+		result.Body, result.ContentType = httpBodySample, httpBodySampleContentType
+	case len(b.Body.FieldPath) == 0:
+		if err := s.fill(root, bound, 0); err != nil {
+			return nil, err
+		}
+		result.Body, result.ContentType = s.bodyJSON(root), "application/json"
+	case b.Body.FieldPath[len(b.Body.FieldPath)-1].Target.GetTypeName() == httpBodyType:
+		path := b.Body.FieldPath
+		target := path[len(path)-1].Target
+		bound[path[0].Name] = true
+		msg, err := s.reg.LookupMsg(target.Message.FQMN(), target.GetTypeName())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.set(root, path, &sampleField{field: target, msg: s.httpBody(msg)}); err != nil {
+			return nil, err
+		}
+		result.Body, result.ContentType = httpBodySample, httpBodySampleContentType
+	default:
+		path := b.Body.FieldPath
+		target := path[len(path)-1].Target
+		bound[path[0].Name] = true
+		if !s.sampleable(target) {
+			return skip("body field %s of type %s is not synthesized", path, target.GetTypeName())
+		}
+		var sf *sampleField
+		if target.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			// the fields of the body already bound by the path keep their values.
+			m := root
+			for _, c := range path {
+				var err error
+				if sf, err = s.lookup(m, c.Target); err != nil {
+					return nil, err
+				}
+				m = sf.msg
+			}
+			sampled := make(map[string]bool)
+			for _, f := range sf.msg.fields {
+				sampled[f.field.GetName()] = true
+			}
+			if err := s.fill(sf.msg, sampled, 1); err != nil {
+				return nil, err
+			}
+		} else if sf = s.scalar(target); sf == nil {
+			return skip("body field %s of type %s is not synthesized", path, target.GetType())
+		}
+		markBody(sf)
+		if err := s.set(root, path, sf); err != nil {
+			return nil, err
+		}
+		result.Body, result.ContentType = s.fieldJSON(sf), "application/json"
+
+		if mask != nil {
+			if err := s.updateMask(root, sf); err != nil {
+				return nil, err
+			}
+			result.OutputOnlyBody = s.outputOnlyBody(b, sf, result.Body)
+		}
+	}
+
+	if b.Body == nil || len(b.Body.FieldPath) != 0 {
+		query := url.Values{}
+		for _, f := range b.Method.RequestType.Fields {
+			if bound[f.GetName()] || !s.sampleable(f) || f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE ||
+				f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+				continue
+			}
+			sf := s.scalar(f)
+			if sf == nil || sf.text == "" {
+				continue
+			}
+			if err := s.set(root, nil, sf); err != nil {
+				return nil, err
+			}
+			query.Set(f.GetName(), sf.text)
+		}
+		if len(query) > 0 {
+			result.URL += "?" + query.Encode()
+		}
+	}
+
+	if fb := bindingFieldBehaviors(b, false); fb != nil {
+		for _, path := range fb.Required {
+			if !sampled(root, path) {
+				return skip("required field %s is not synthesized", path)
+			}
+		}
+	}
+
+	result.Want = s.goExpr(root)
+	return result, nil
+}
+
+// outputOnlyJSON are the JSON values the well-known types of OUTPUT_ONLY fields are sampled with.
+var outputOnlyJSON = map[string]string{
+	".google.protobuf.Timestamp": `"2006-01-02T15:04:05Z"`,
+	".google.protobuf.Duration":  `"1s"`,
+}
+
+// outputOnlyBody returns the JSON body "body" of the field "sf" of "b" with its OUTPUT_ONLY fields, or an
+// empty string if it has none. Only the fields of the body itself are sampled, not the ones nested in it.
+func (s *sampler) outputOnlyBody(b *gwdescriptor.Binding, sf *sampleField, body string) string {
+	fb := bindingFieldBehaviors(b, false)
+	if fb == nil || sf.msg == nil {
+		return ""
+	}
+	var fields []string
+	for _, path := range fb.Unmasked {
+		for _, f := range sf.msg.msg.Fields {
+			if f.GetName() != path || f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+				continue
+			}
+			value := outputOnlyJSON[f.GetTypeName()]
+			if value == "" && f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+				if v := s.scalar(f); v != nil {
+					value = v.json
+				}
+			}
+			if value != "" {
+				fields = append(fields, fmt.Sprintf("%q:%s", f.GetName(), value))
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	if body == "{}" {
+		return "{" + strings.Join(fields, ",") + "}"
+	}
+	return strings.TrimSuffix(body, "}") + "," + strings.Join(fields, ",") + "}"
+}
+
+// sampled reports whether the field at "path" within "m" is sampled, or a message on the path is not,
+// as RequireFields requires it.
+func sampled(m *sampleMessage, path string) bool {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		var found *sampleField
+		for _, sf := range m.fields {
+			if sf.field.GetName() == name {
+				found = sf
+			}
+		}
+		if found == nil || found.msg == nil {
+			return found != nil || i < len(names)-1
+		}
+		m = found.msg
+	}
+	return true
+}
+
+// updateMask samples the FieldMask of "root" filled from the fields of the body "body".
+func (s *sampler) updateMask(root *sampleMessage, body *sampleField) error {
+	for _, f := range root.msg.Fields {
+		if f.GetTypeName() != ".google.protobuf.FieldMask" {
+			continue
+		}
+		var paths []string
+		for _, p := range maskPaths(body.msg, "") {
+			paths = append(paths, strconv.Quote(p))
+		}
+		s.add(gwdescriptor.GoPackage{Path: "google.golang.org/protobuf/types/known/fieldmaskpb", Name: "fieldmaskpb"})
+		expr := fmt.Sprintf("&fieldmaskpb.FieldMask{Paths: []string{%s}}", strings.Join(paths, ", "))
+		return s.set(root, nil, &sampleField{field: f, expr: expr})
+	}
+	return nil
+}
+
+// maskPaths returns the field mask paths of the fields of "m" sent in the body, prefixed with "prefix",
+// in the order UpdateMask masks them: sorted by name, nested messages being masked by their fields.
+func maskPaths(m *sampleMessage, prefix string) []string {
+	fields := make([]*sampleField, 0, len(m.fields))
+	for _, sf := range m.fields {
+		if sf.inBody {
+			fields = append(fields, sf)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].field.GetName() < fields[j].field.GetName()
+	})
+	var paths []string
+	for _, sf := range fields {
+		path := prefix + sf.field.GetName()
+		if sf.msg != nil && len(sf.msg.fields) != 0 && sf.field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			paths = append(paths, maskPaths(sf.msg, path+".")...)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// fillPlaceholders replaces wildcard placeholders in a path with sample segments.
+func fillPlaceholders(path string) string {
+	for i := 1; strings.Contains(path, wildcardPlaceholder); i++ {
+		path = strings.Replace(path, wildcardPlaceholder, fmt.Sprintf("x%d", i), 1)
+	}
+	return strings.Replace(path, deepWildcardPlaceholder, "y1/y2", -1)
+}
@@ -0,0 +1,123 @@
+package gengateway
+
+import (
+	"fmt"
+	"strings"
+
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	"github.com/thesoulless/protoc-gen-gokitmux/internal/casing"
+)
+
+// Modes the status codes of the successful responses are selected in, besides the gokitmux.status
+// option of the methods.
+const (
+	// successStatusAIP infers them from the standard methods of the AIPs: 201 Created for the Create
+	// methods, and 204 No Content for the methods returning google.protobuf.Empty.
+	successStatusAIP = "aip"
+	// successStatusOK writes 200 OK.
+	successStatusOK = "ok"
+)
+
+// responseStatus is the status code of the successful responses of a binding, other than 200 OK.
+type responseStatus struct {
+	// Code is the status code.
+	Code int
+	// Location is the go name of the field of the response holding the name of the resource it
+	// creates, which the Location header is built from. It is empty if there is none.
+	Location string
+}
+
+// bindingResponseStatus returns the status code of the successful responses of "b" in "mode", or nil if
+// it is 200 OK. The gokitmux.status option of the method overrides the one of "mode", its code being
+// the inferred one if it is unset.
+func bindingResponseStatus(mode string, b *descriptor.Binding) (*responseStatus, error) {
+	m := b.Method
+	var result responseStatus
+	if mode == successStatusAIP {
+		switch resp := m.ResponseType; {
+		case resp.FQMN() == ".google.protobuf.Empty":
+			result.Code = 204
+		case strings.HasPrefix(m.GetName(), "Create") && b.HTTPMethod == "POST" && resp.FQMN() != ".google.longrunning.Operation":
+			result.Code = 201
+			result.Location = nameField(resp)
+		}
+	}
+	if opts := m.ResponseStatus; opts != nil {
+		if code := int(opts.GetCode()); code != 0 {
+			if code < 200 || code > 299 {
+				return nil, fmt.Errorf("gokitmux.status of %s.%s: %d is not a success status code", m.Service.GetName(), m.GetName(), code)
+			}
+			result.Code = code
+		}
+		if name := opts.GetLocationField(); name != "" {
+			f := lookupMessageField(m.ResponseType, name)
+			if f == nil || f.GetType() != protodescriptor.FieldDescriptorProto_TYPE_STRING || f.GetLabel() == protodescriptor.FieldDescriptorProto_LABEL_REPEATED {
+				return nil, fmt.Errorf("gokitmux.status of %s.%s: location_field %s is not a string field of %s", m.Service.GetName(), m.GetName(), name, m.ResponseType.GetName())
+			}
+			result.Location = casing.Camel(name)
+		}
+	}
+	if result.Code == 0 {
+		result.Code = 200
+	}
+	if result.Code == 200 && result.Location == "" {
+		return nil, nil
+	}
+	return &result, nil
+}
+
+// nameField returns the go name of the field of "msg" holding its resource name: the name field of its
+// google.api.resource, or a string field called name. It returns an empty string if it has none.
+func nameField(msg *descriptor.Message) string {
+	name := "name"
+	if msg.Resource != nil {
+		name = msg.Resource.NameField()
+	}
+	f := lookupMessageField(msg, name)
+	if f == nil || f.GetType() != protodescriptor.FieldDescriptorProto_TYPE_STRING || f.GetLabel() == protodescriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return ""
+	}
+	return casing.Camel(name)
+}
+
+// lookupMessageField returns the field of "msg" called "name", or nil if there is none.
+func lookupMessageField(msg *descriptor.Message, name string) *descriptor.Field {
+	for _, f := range msg.Fields {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// hasResponseLocation reports whether the responses of any binding of "m" in "mode" have a Location header.
+func hasResponseLocation(mode string, m *descriptor.Method) bool {
+	for _, b := range m.Bindings {
+		if s, err := bindingResponseStatus(mode, b); err == nil && s != nil && s.Location != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiatesResponse reports whether the media type of the responses of "m" is negotiated with the
+// Accept header of the requests. The ones of google.api.HttpBody have the content type of their data.
+func negotiatesResponse(m *descriptor.Method) bool {
+	return m.ResponseType.FQMN() != httpBodyType
+}
+
+// requestDecoder returns the request decoder of the handler of "b" in the package "pkg": its decode method if
+// it has one, see decodeDoc, or its Decode method. It is wrapped with NegotiateResponse if "negotiate" and the
+// media type of the responses is negotiated.
+func requestDecoder(pkg string, b *descriptor.Binding, validate, rejectImmutable, negotiate bool) string {
+	decode := "e.Decode"
+	if decodeDoc(b, validate, rejectImmutable) != "" {
+		decode = "e.decode"
+	}
+	if negotiate && negotiatesResponse(b.Method) {
+		return fmt.Sprintf("%s.NegotiateResponse(%s)", pkg, decode)
+	}
+	return decode
+}
@@ -0,0 +1,98 @@
+package gengateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
+)
+
+func TestBindingResponseStatus(t *testing.T) {
+	stringField := func(name string) *descriptor.Field {
+		return &descriptor.Field{
+			FieldDescriptorProto: &protodescriptor.FieldDescriptorProto{
+				Name:  proto.String(name),
+				Type:  protodescriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label: protodescriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			},
+		}
+	}
+	message := func(pkg, name string, fields ...*descriptor.Field) *descriptor.Message {
+		return &descriptor.Message{
+			File:            &descriptor.File{FileDescriptorProto: &protodescriptor.FileDescriptorProto{Package: proto.String(pkg)}},
+			DescriptorProto: &protodescriptor.DescriptorProto{Name: proto.String(name)},
+			Fields:          fields,
+		}
+	}
+	book := message("example", "Book", stringField("name"), stringField("path"))
+	empty := message("google.protobuf", "Empty")
+	svc := &descriptor.Service{ServiceDescriptorProto: &protodescriptor.ServiceDescriptorProto{Name: proto.String("Library")}}
+
+	for _, spec := range []struct {
+		name       string
+		httpMethod string
+		response   *descriptor.Message
+		opts       *gmoptions.ResponseStatus
+		mode       string
+		want       *responseStatus
+	}{
+		{name: "GetBook", httpMethod: "GET", response: book, mode: successStatusAIP},
+		{name: "CreateBook", httpMethod: "POST", response: book, mode: successStatusAIP, want: &responseStatus{Code: 201, Location: "Name"}},
+		{name: "CreateBook", httpMethod: "PUT", response: book, mode: successStatusAIP},
+		{name: "CreateBook", httpMethod: "POST", response: book, mode: successStatusOK},
+		{name: "DeleteBook", httpMethod: "DELETE", response: empty, mode: successStatusAIP, want: &responseStatus{Code: 204}},
+		{name: "DeleteBook", httpMethod: "DELETE", response: empty, mode: successStatusOK},
+		{
+			name: "ArchiveBook", httpMethod: "POST", response: book, mode: successStatusAIP,
+			opts: &gmoptions.ResponseStatus{Code: 202},
+			want: &responseStatus{Code: 202},
+		},
+		{
+			name: "CreateBook", httpMethod: "POST", response: book, mode: successStatusOK,
+			opts: &gmoptions.ResponseStatus{LocationField: "path"},
+			want: &responseStatus{Code: 200, Location: "Path"},
+		},
+		{
+			name: "CreateBook", httpMethod: "POST", response: book, mode: successStatusAIP,
+			opts: &gmoptions.ResponseStatus{Code: 200},
+			want: &responseStatus{Code: 200, Location: "Name"},
+		},
+	} {
+		b := &descriptor.Binding{
+			Method: &descriptor.Method{
+				MethodDescriptorProto: &protodescriptor.MethodDescriptorProto{Name: proto.String(spec.name)},
+				Service:               svc,
+				ResponseType:          spec.response,
+				ResponseStatus:        spec.opts,
+			},
+			HTTPMethod: spec.httpMethod,
+		}
+		got, err := bindingResponseStatus(spec.mode, b)
+		if err != nil {
+			t.Errorf("bindingResponseStatus(%q, %s %s) failed with %v; want success", spec.mode, spec.httpMethod, spec.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("bindingResponseStatus(%q, %s %s) = %+v; want %+v", spec.mode, spec.httpMethod, spec.name, got, spec.want)
+		}
+	}
+
+	for _, opts := range []*gmoptions.ResponseStatus{{Code: 404}, {LocationField: "missing"}} {
+		b := &descriptor.Binding{
+			Method: &descriptor.Method{
+				MethodDescriptorProto: &protodescriptor.MethodDescriptorProto{Name: proto.String("GetBook")},
+				Service:               svc,
+				ResponseType:          book,
+				ResponseStatus:        opts,
+			},
+			HTTPMethod: "GET",
+		}
+		if got, err := bindingResponseStatus(successStatusAIP, b); err == nil {
+			t.Errorf("bindingResponseStatus(%v) = %+v; want an error", opts, got)
+		}
+	}
+}
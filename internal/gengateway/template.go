@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/golang/glog"
+	protodescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/grpc-ecosystem/grpc-gateway/utilities"
 	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
 	"github.com/thesoulless/protoc-gen-gokitmux/internal/casing"
@@ -17,19 +19,49 @@ import (
 
 type param struct {
 	*descriptor.File
-	Imports            []descriptor.GoPackage
-	RegisterFuncSuffix string
-	AllowPatchFeature  bool
-	Metrics            string
-	ErrorEncoder       string
-	PackageName        string
+	Imports              []descriptor.GoPackage
+	RegisterFuncSuffix   string
+	AllowPatchFeature    bool
+	Metrics              string
+	ErrorEncoder         string
+	PackageName          string
+	ModuleName           string
+	SplitServices        bool
+	RequireUnimplemented bool
+	GRPCClient           bool
+	GRPCServer           bool
+	Router               string
+	Transport            string
+	Validate             bool
+	RejectImmutable      bool
+	NegotiateAccept      bool
+	SuccessStatus        string
 }
 
 type params struct {
-	Files       []*descriptor.File
-	Imports     []descriptor.GoPackage
-	Metrics     string
-	PackageName string
+	Files                []*descriptor.File
+	Imports              []descriptor.GoPackage
+	Metrics              string
+	ErrorEncoder         string
+	PackageName          string
+	ModuleName           string
+	RequireUnimplemented bool
+	SplitServices        bool
+	Router               string
+	Transport            string
+	// CORS is whether the routes answer cross-origin requests.
+	CORS bool
+	// Validate is whether the requests are validated with gen.ValidateRequest.
+	Validate bool
+	// NegotiateAccept is whether the Accept header of the requests is negotiated before calling the services.
+	NegotiateAccept bool
+	// EmitUnpopulated, UseProtoNames, UseEnumNumbers and DiscardUnknown are the options of the marshalers.
+	EmitUnpopulated bool
+	UseProtoNames   bool
+	UseEnumNumbers  bool
+	DiscardUnknown  bool
+	// Multipart is whether multipart/form-data bodies are decoded.
+	Multipart bool
 }
 
 type binding struct {
@@ -139,6 +171,111 @@ func (b binding) FieldMaskField() string {
 	return ""
 }
 
+// updateMask is the FieldMask of a partial update filled from the fields present in its body.
+type updateMask struct {
+	// Field is the go name of the FieldMask field of the request.
+	Field string
+	// Body is the go name of the request field the body is decoded into.
+	Body string
+}
+
+// bindingUpdateMask returns the update mask of "b", or nil if it has none. PATCH bindings whose body is
+// a message field of the request have one if the request has exactly one FieldMask, as in AIP-134.
+func bindingUpdateMask(b *descriptor.Binding) *updateMask {
+	if b.HTTPMethod != "PATCH" || b.Body == nil || len(b.Body.FieldPath) != 1 {
+		return nil
+	}
+	body := b.Body.FieldPath[0].Target
+	if body.GetType() != protodescriptor.FieldDescriptorProto_TYPE_MESSAGE || body.GetTypeName() == ".google.protobuf.FieldMask" {
+		return nil
+	}
+	field := binding{Binding: b}.FieldMaskField()
+	if field == "" {
+		return nil
+	}
+	return &updateMask{Field: field, Body: casing.Camel(body.GetName())}
+}
+
+// hasUpdateMasks reports whether any binding of "services" has an update mask.
+func hasUpdateMasks(services []*descriptor.Service) bool {
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				if bindingUpdateMask(b) != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// decodeDoc returns the doc comment of the decode method wrapping Decode for "b", listing what it does
+// before and after it, validating the request if "validate" is set and rejecting the update masks masking
+// immutable fields if "rejectImmutable" is. It returns an empty string if "b" needs no wrapper.
+func decodeDoc(b *descriptor.Binding, validate, rejectImmutable bool) string {
+	var steps []string
+	if bindingHasResourceParams(b) {
+		steps = append(steps, "checks the resource names in the path")
+	}
+	steps = append(steps, "decodes the request with Decode")
+	if len(b.HeaderParams) != 0 {
+		steps = append(steps, "sets the fields bound to headers and cookies")
+	}
+	if bindingUpdateMask(b) != nil {
+		steps = append(steps, "sets the update mask to the fields present in the body if the client sent none")
+	}
+	if bindingFieldBehaviors(b, rejectImmutable) != nil {
+		steps = append(steps, "enforces the google.api.field_behavior of the fields")
+	}
+	if validate {
+		steps = append(steps, "validates the request")
+	}
+	if len(steps) == 1 {
+		return ""
+	}
+	return "decode " + strings.Join(steps[:len(steps)-1], ", ") + " and " + steps[len(steps)-1] + "."
+}
+
+// serviceName returns the name of the interface of "svc" when services are split.
+// It is the name of the service suffixed with "Service", unless it already ends with it.
+func serviceName(svc *descriptor.Service) string {
+	return strings.TrimSuffix(casing.Camel(svc.GetName()), "Service") + "Service"
+}
+
+// packageRef returns the identifier "pkg" is referred to by in the files importing it.
+func packageRef(pkg descriptor.GoPackage) string {
+	if pkg.Alias != "" {
+		return pkg.Alias
+	}
+	return pkg.Name
+}
+
+// boundServices returns the services of "files" with at least one binding.
+func boundServices(files []*descriptor.File) []*descriptor.Service {
+	var result []*descriptor.Service
+	for _, f := range files {
+		for _, svc := range f.Services {
+			for _, m := range svc.Methods {
+				if len(m.Bindings) > 0 {
+					result = append(result, svc)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// handlerName returns the name of the handler type serving "b".
+// Additional bindings of a method are suffixed with their index, so that each gets its own type.
+func handlerName(b *descriptor.Binding) string {
+	if b.Index == 0 {
+		return b.Method.GetName()
+	}
+	return fmt.Sprintf("%s%d", b.Method.GetName(), b.Index)
+}
+
 // queryParamFilter is a wrapper of utilities.DoubleArray which provides String() to output DoubleArray.Encoding in a stable and predictable format.
 type queryParamFilter struct {
 	*utilities.DoubleArray
@@ -156,22 +293,30 @@ func (f queryParamFilter) String() string {
 type trailerParams struct {
 	Files              []*descriptor.File
 	Services           []*descriptor.Service
+	Metrics            string
 	ErrorEncoder       string
 	PackageName        string
 	RegisterFuncSuffix string
+	SplitServices      bool
+	// Sets are the services endpoint sets are generated for.
+	Sets                 []gatewayService
+	RequireUnimplemented bool
+	Router               string
+	Transport            string
+	Validate             bool
+	RejectImmutable      bool
+	NegotiateAccept      bool
+	SuccessStatus        string
+	// RouteMethods are the HTTP methods the methods allowed on a path are looked up among.
+	RouteMethods []string
+	CORS         bool
 }
 
 func applyTemplate(p param) (string, error) {
-	moduleName := readModuleName()
 	w := bytes.NewBuffer(nil)
 	p.Imports = []descriptor.GoPackage{
 		{
-			Path:  "github.com/go-kit/kit/transport/http",
-			Name:  "http",
-			Alias: "httptransport",
-		},
-		{
-			Path: moduleName + "/" + p.PackageName,
+			Path: p.ModuleName + "/" + p.PackageName,
 		},
 	}
 	if p.Metrics != "" {
@@ -180,9 +325,6 @@ func applyTemplate(p param) (string, error) {
 		})
 	}
 
-	if err := kitHeaderTemplate.Execute(w, p); err != nil {
-		return "", err
-	}
 	var targetServices []*descriptor.Service
 
 	for _, msg := range p.Messages {
@@ -209,11 +351,60 @@ func applyTemplate(p param) (string, error) {
 		return "", errNoTargetService
 	}
 
+	imports := make(goImports)
+	sets := gatewayServices([]*descriptor.File{p.File}, imports)
+	p.Imports = append(p.Imports, descriptor.GoPackage{Path: "context"})
+	if p.Transport == transportNetHTTP {
+		// the handlers only refer to the request types, and endpoint sets are go-kit's.
+		sets = nil
+		imports = make(goImports)
+		for _, svc := range targetServices {
+			for _, m := range svc.Methods {
+				if len(m.Bindings) != 0 {
+					imports.goType(m.RequestType)
+				}
+				if hasResponseLocation(p.SuccessStatus, m) {
+					imports.goType(m.ResponseType)
+				}
+			}
+		}
+	} else {
+		p.Imports = append(p.Imports,
+			descriptor.GoPackage{Path: "github.com/go-kit/kit/endpoint"},
+			descriptor.GoPackage{
+				Path:  "github.com/go-kit/kit/transport/http",
+				Name:  "http",
+				Alias: "httptransport",
+			},
+		)
+	}
+	if p.Transport != transportNetHTTP || p.Validate || hasHeaderParams(targetServices) || hasUpdateMasks(targetServices) || hasFieldBehaviors(targetServices, p.RejectImmutable) || hasResourceParams(targetServices) {
+		p.Imports = append(p.Imports, descriptor.GoPackage{Path: "net/http"})
+		p.Imports = append(p.Imports, headerParamImports(targetServices)...)
+	}
+	if hasQueryParams(targetServices) {
+		p.Imports = append(p.Imports, descriptor.GoPackage{Path: "github.com/grpc-ecosystem/grpc-gateway/utilities"})
+	}
+	p.Imports = append(p.Imports, imports.list()...)
+	if err := kitHeaderTemplate.Execute(w, p); err != nil {
+		return "", err
+	}
+
 	tp := trailerParams{
-		Services:           targetServices,
-		ErrorEncoder:       p.ErrorEncoder,
-		PackageName:        p.PackageName,
-		RegisterFuncSuffix: p.RegisterFuncSuffix,
+		Services:             targetServices,
+		Metrics:              p.Metrics,
+		ErrorEncoder:         p.ErrorEncoder,
+		PackageName:          p.PackageName,
+		RegisterFuncSuffix:   p.RegisterFuncSuffix,
+		SplitServices:        p.SplitServices,
+		Sets:                 sets,
+		RequireUnimplemented: p.RequireUnimplemented,
+		Router:               p.Router,
+		Transport:            p.Transport,
+		Validate:             p.Validate,
+		RejectImmutable:      p.RejectImmutable,
+		NegotiateAccept:      p.NegotiateAccept,
+		SuccessStatus:        p.SuccessStatus,
 	}
 	if err := kitTemplate.Execute(w, tp); err != nil {
 		return "", err
@@ -221,19 +412,38 @@ func applyTemplate(p param) (string, error) {
 	return w.String(), nil
 }
 
+// hasQueryParams reports whether a binding of "services" has parameters in the query string.
+func hasQueryParams(services []*descriptor.Service) bool {
+	for _, svc := range services {
+		for _, m := range svc.Methods {
+			for _, b := range m.Bindings {
+				if (binding{Binding: b}).HasQueryParam() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 func applyRoutesTemplate(ps params) (string, error) {
 	w := bytes.NewBuffer(nil)
-	ps.Imports = []descriptor.GoPackage{
-		{
-			Path: "github.com/gorilla/mux",
-		},
+	ps.Imports = append(append([]descriptor.GoPackage(nil), routerImports[ps.Router]...), routingImports...)
+	if ps.Transport != transportNetHTTP {
+		ps.Imports = append(ps.Imports, descriptor.GoPackage{Path: "github.com/go-kit/kit/endpoint"})
 	}
 	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
 		return "", err
 	}
 
+	services := boundServices(ps.Files)
 	tp := trailerParams{
-		Files: ps.Files,
+		Services:      services,
+		SplitServices: ps.SplitServices,
+		Router:        ps.Router,
+		Transport:     ps.Transport,
+		RouteMethods:  routeMethods(services),
+		CORS:          ps.CORS,
 	}
 	if err := routesTemplate.Execute(w, tp); err != nil {
 		return "", err
@@ -242,11 +452,10 @@ func applyRoutesTemplate(ps params) (string, error) {
 }
 
 func applyMuxkitTemplate(ps params) (string, error) {
-	moduleName := readModuleName()
 	w := bytes.NewBuffer(nil)
 	for _, f := range ps.Files {
 		ps.Imports = append(ps.Imports, descriptor.GoPackage{
-			Path: fmt.Sprintf("%s/%s/%s", moduleName, ps.PackageName, *f.Package),
+			Path: fmt.Sprintf("%s/%s/%s", ps.ModuleName, ps.PackageName, *f.Package),
 		})
 	}
 	if err := muxkitHeaderTemplate.Execute(w, ps); err != nil {
@@ -268,157 +477,1221 @@ func applyEndpointsTemplate(ps params) (string, error) {
 		{
 			Path: "context",
 		},
-		{
-			Path: "github.com/go-kit/kit/endpoint",
-		},
 		{
 			Path: "net/http",
 		},
 	}
 
+	if ps.Transport != transportNetHTTP {
+		ps.Imports = append(ps.Imports, descriptor.GoPackage{Path: "github.com/go-kit/kit/endpoint"})
+	}
+
 	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
 		return "", err
 	}
 
-	tp := trailerParams{}
+	tp := trailerParams{
+		Services:      boundServices(ps.Files),
+		SplitServices: ps.SplitServices,
+		Router:        ps.Router,
+		Transport:     ps.Transport,
+	}
 	if err := endpointsTemplate.Execute(w, tp); err != nil {
 		return "", err
 	}
 	return w.String(), nil
 }
 
-func readModuleName() string {
-	file, err := os.Open("go.mod")
-	if err != nil {
-		panic(err)
-	}
-	r := bufio.NewReader(file)
-	line, _, err := r.ReadLine()
-	if err != nil {
-		panic(err)
-	}
-	moduleName := bytes.TrimPrefix(line, []byte("module "))
-	return string(moduleName)
-}
+// goImports collects the go packages of the types referenced by generated code.
+type goImports map[string]descriptor.GoPackage
 
-var (
-	funcs = template.FuncMap{"ToLower": strings.ToLower}
+func (imports goImports) add(pkg descriptor.GoPackage) {
+	imports[pkg.Path] = pkg
+}
 
-	kitHeaderTemplate = template.Must(template.New("header").Parse(`
-// Code generated by protoc-gen-gokitmux. DO NOT EDIT.
-// source: {{.GetName}}
+// goType returns the go type name of "msg" outside of its package, importing it.
+func (imports goImports) goType(msg *descriptor.Message) string {
+	imports.add(msg.File.GoPkg)
+	return msg.GoType("")
+}
 
-/*
-Package {{.GoPkg.Name}} is a reverse proxy.
+// list returns the collected packages sorted by path.
+func (imports goImports) list() []descriptor.GoPackage {
+	var result []descriptor.GoPackage
+	for _, pkg := range imports {
+		result = append(result, pkg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
 
-It translates gRPC into RESTful JSON APIs.
-*/
-package {{.GoPkg.Name | printf "%s\n"}}
+// serviceMethod is a method of the GatewayService.
+type serviceMethod struct {
+	*descriptor.Method
+	RequestType  string
+	ResponseType string
+}
 
-import (
-	{{range $i := .Imports}}{{if $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+// gatewayService is a service served by the gateway.
+type gatewayService struct {
+	*descriptor.Service
+	Methods []serviceMethod
+}
 
-	{{range $i := .Imports}}{{if not $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
-)
-`))
+// gatewayServices returns the services of "files" with bindings, with their methods with bindings.
+func gatewayServices(files []*descriptor.File, imports goImports) []gatewayService {
+	var result []gatewayService
+	for _, svc := range boundServices(files) {
+		s := gatewayService{Service: svc}
+		for _, m := range svc.Methods {
+			if len(m.Bindings) == 0 {
+				continue
+			}
+			s.Methods = append(s.Methods, serviceMethod{
+				Method:       m,
+				RequestType:  imports.goType(m.RequestType),
+				ResponseType: imports.goType(m.ResponseType),
+			})
+		}
+		result = append(result, s)
+	}
+	return result
+}
 
-	serviceHeaderTemplate = template.Must(template.New("header").Parse(`
-// Code generated by protoc-gen-gokitmux. DO NOT EDIT.
+type serviceParams struct {
+	Services             []gatewayService
+	PackageName          string
+	RequireUnimplemented bool
+	SplitServices        bool
+}
 
-package {{.PackageName | printf "%s\n"}}
-import (
-	{{range $i := .Imports}}{{if $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+// Methods returns the methods of all the services.
+func (p serviceParams) Methods() []serviceMethod {
+	var result []serviceMethod
+	for _, svc := range p.Services {
+		result = append(result, svc.Methods...)
+	}
+	return result
+}
 
-	{{range $i := .Imports}}{{if not $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
-)
-`))
+func applyServiceTemplate(ps params) (string, error) {
+	imports := make(goImports)
+	tp := serviceParams{
+		Services:             gatewayServices(ps.Files, imports),
+		RequireUnimplemented: ps.RequireUnimplemented,
+		SplitServices:        ps.SplitServices,
+	}
 
-	muxkitHeaderTemplate = template.Must(template.New("header").Parse(`
-// Code generated by protoc-gen-gokitmux. DO NOT EDIT.
+	w := bytes.NewBuffer(nil)
+	ps.Imports = append([]descriptor.GoPackage{{Path: "context"}}, imports.list()...)
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := serviceTemplate.Execute(w, tp); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
 
-package muxkit
+func applyUnimplementedTemplate(ps params) (string, error) {
+	imports := make(goImports)
+	tp := serviceParams{
+		Services:             gatewayServices(ps.Files, imports),
+		RequireUnimplemented: ps.RequireUnimplemented,
+		SplitServices:        ps.SplitServices,
+	}
 
-import (
-	{{range $i := .Imports}}{{if $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "context"},
+		{Path: "fmt"},
+		{Path: "net/http"},
+	}
+	ps.Imports = append(ps.Imports, imports.list()...)
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := unimplementedTemplate.Execute(w, tp); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
 
-	{{range $i := .Imports}}{{if not $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
-)
-`))
+func applyContextTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "context"},
+		{Path: "errors"},
+		{Path: "io"},
+		{Path: "net/http"},
+		{Path: "strings"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := contextTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
 
-	kitTemplate = template.Must(template.New("kit").Funcs(funcs).Parse(`
-{{$ErrorEncoder := .ErrorEncoder}}
-{{$PackageName := .PackageName}}
-func New() {
-	{{range $i, $svc := .Services}}
-	{{range $j, $m := $svc.Methods}}
-	{{range $k, $b := $m.Bindings}}
-	h{{$i}}{{$j}}{{$k}} := &{{$m.GetName}}{}
-	{{$PackageName}}.RegisterHandler(h{{$i}}{{$j}}{{$k}})
-	{{end}}
-	{{end}}
-	{{end}}
+func applyMarshalTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "bytes"},
+		{Path: "context"},
+		{Path: "encoding/base64"},
+		{Path: "encoding/json"},
+		{Path: "fmt"},
+		{Path: "io"},
+		{Path: "mime"},
+		{Path: "net/http"},
+		{Path: "net/url"},
+		{Path: "sort"},
+		{Path: "strconv"},
+		{Path: "strings"},
+		{Path: "github.com/grpc-ecosystem/grpc-gateway/utilities"},
+		{Path: "google.golang.org/genproto/googleapis/api/httpbody"},
+		{Path: "google.golang.org/genproto/googleapis/rpc/errdetails"},
+		{Path: "google.golang.org/grpc/codes"},
+		{Path: "google.golang.org/grpc/status"},
+		{Path: "google.golang.org/protobuf/encoding/protojson"},
+		{Path: "google.golang.org/protobuf/proto"},
+		{Path: "google.golang.org/protobuf/reflect/protoreflect"},
+		{Path: "google.golang.org/protobuf/types/known/fieldmaskpb"},
+	}
+	if ps.Multipart {
+		ps.Imports = append(ps.Imports, descriptor.GoPackage{Path: "mime/multipart"})
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := marshalTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
 }
-{{range $svc := .Services}}
-	{{range $m := $svc.Methods}}
-	{{range $b := $m.Bindings}}
-	type {{$m.GetName}} struct{}
-	{{end}}
-	{{end}}
-{{end}}
-{{range $svc := .Services}}
-	{{range $m := $svc.Methods}}
-	{{range $b := $m.Bindings}}
-	func (e *{{$m.GetName}}) Register(svc {{$PackageName}}.GatewayService) *{{$PackageName}}.Route {
-		{{$m.GetName}}{{$.RegisterFuncSuffix}} := httptransport.NewServer(
-			e.Make(svc),
-			e.Decode,
-			e.Encode,
-			{{if $ErrorEncoder}}httptransport.ServerErrorEncoder({{$ErrorEncoder}}),{{end}}
-		)
-		{{$svc.GetName}}{{$.RegisterFuncSuffix}}Client := metrics.ForHandler(
-			e.ForHandler({{$m.GetName}}{{$.RegisterFuncSuffix}}),
-			"{{$m.GetName}}",
-		)
-		
-		r := &{{$PackageName}}.Route{
-			Path: {{$b.PathTmpl.Template | printf "%q"}},
-			Handler: {{$svc.GetName}}{{$.RegisterFuncSuffix}}Client,
-			Method: {{$b.HTTPMethod | printf "%q"}},
-			{{with $n := $m.GetName }}Name: {{ ToLower $n | printf "%q"}},{{end}}
-		}
 
-		return r
+func applyBehaviorTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "strings"},
+		{Path: "google.golang.org/genproto/googleapis/rpc/errdetails"},
+		{Path: "google.golang.org/protobuf/proto"},
+		{Path: "google.golang.org/protobuf/reflect/protoreflect"},
+		{Path: "google.golang.org/protobuf/types/known/fieldmaskpb"},
 	}
-	{{end}}
-	{{end}}
-{{end}}`))
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := behaviorTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
 
-	serviceTemplate = template.Must(template.New("kit").Funcs(funcs).Parse(`
-type GatewayService interface { {{range $f := .Files}}
-{{range $svc := .Services}}// {{$svc.GetName}}
-{{range $m := $svc.Methods}}{{range $b := $m.Bindings}}{{$m.GetName}}(context.Context, *{{$svc.File.Package}}.{{$b.Method.RequestType.Name}}) (*{{$svc.File.Package}}.{{$b.Method.ResponseType.Name}}, error)
-{{end}}{{end}}
-{{end}}
-{{end}}
-}`))
+func applyValidateTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "errors"},
+		{Path: "fmt"},
+		{Path: "reflect"},
+		{Path: "strings"},
+		{Path: "google.golang.org/genproto/googleapis/rpc/errdetails"},
+		{Path: "google.golang.org/protobuf/proto"},
+		{Path: "google.golang.org/protobuf/reflect/protoreflect"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := validateTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
 
-	routesTemplate = template.Must(template.New("kit").Funcs(funcs).Parse(`
-func Router(svc GatewayService) *mux.Router {
-	r := mux.NewRouter()
+func applyClientTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "bytes"},
+		{Path: "encoding/json"},
+		{Path: "fmt"},
+		{Path: "io"},
+		{Path: "net/http"},
+		{Path: "net/url"},
+		{Path: "regexp"},
+		{Path: "strings"},
+		{Path: "google.golang.org/genproto/googleapis/api/httpbody"},
+		{Path: "google.golang.org/genproto/googleapis/rpc/status", Alias: "spb"},
+		{Path: "google.golang.org/grpc/status"},
+		{Path: "google.golang.org/protobuf/encoding/protojson"},
+		{Path: "google.golang.org/protobuf/proto"},
+		{Path: "google.golang.org/protobuf/reflect/protoreflect"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := clientTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
 
-	for _, h := range Handlers {
-		route := h.Register(svc)
-		muxRoute := r.Handle(route.Path, route.Handler).Methods(route.Method)
+type resourcesParams struct {
+	Resources []*resource
+}
 
-		if route.Name != "" {
-			muxRoute.Name(route.Name)
-		}
+func applyResourcesTemplate(ps params) (string, error) {
+	resources, err := boundResources(boundServices(ps.Files))
+	if err != nil {
+		return "", err
 	}
 
-	r = ManualRouter(svc, r)
-
-	return r
-}`))
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "fmt"},
+		{Path: "net/http"},
+		{Path: "strings"},
+		{Path: "google.golang.org/genproto/googleapis/rpc/errdetails"},
+		{Path: "google.golang.org/grpc/codes"},
+		{Path: "google.golang.org/grpc/status"},
+		{Path: "google.golang.org/protobuf/encoding/protojson"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := resourcesTemplate.Execute(w, resourcesParams{Resources: resources}); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func applyCORSTemplate(ps params, cors corsParams) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "net/http"},
+		{Path: "strconv"},
+		{Path: "strings"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := corsTemplate.Execute(w, cors); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func applyParamsTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "net/http"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := paramsTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func applyMetadataTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "context"},
+		{Path: "net"},
+		{Path: "net/http"},
+		{Path: "net/textproto"},
+		{Path: "strings"},
+		{Path: "google.golang.org/grpc/metadata"},
+		{Path: "google.golang.org/grpc/peer"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := metadataTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func applyTransportTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "context"},
+		{Path: "encoding/json"},
+		{Path: "net/http"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := transportTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func applyGRPCTemplate(ps params) (string, error) {
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "context"},
+		{Path: "net/http"},
+		{Path: "sync"},
+		{Path: "google.golang.org/grpc"},
+		{Path: "google.golang.org/grpc/codes"},
+		{Path: "google.golang.org/grpc/metadata"},
+		{Path: "google.golang.org/grpc/status"},
+	}
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := grpcTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+type adapterParams struct {
+	Services             []gatewayService
+	PackageName          string
+	SplitServices        bool
+	RequireUnimplemented bool
+	GRPCClient           bool
+	GRPCServer           bool
+}
+
+// applyAdaptersTemplate applies the template of the adapters of the gRPC services of "p.File" to the gateway.
+func applyAdaptersTemplate(p param) (string, error) {
+	imports := make(goImports)
+	tp := adapterParams{
+		PackageName:          p.PackageName,
+		SplitServices:        p.SplitServices,
+		RequireUnimplemented: p.RequireUnimplemented,
+		GRPCClient:           p.GRPCClient,
+		GRPCServer:           p.GRPCServer,
+	}
+	tp.Services = gatewayServices([]*descriptor.File{p.File}, imports)
+	if len(tp.Services) == 0 {
+		return "", errNoTargetService
+	}
+	// The gRPC client and server interfaces are generated into the package of the messages.
+	imports.add(p.File.GoPkg)
+
+	w := bytes.NewBuffer(nil)
+	ps := params{
+		PackageName: p.GoPkg.Name,
+		Imports: []descriptor.GoPackage{
+			{Path: "context"},
+			{Path: "google.golang.org/grpc"},
+			{Path: p.ModuleName + "/" + p.PackageName},
+		},
+	}
+	if p.GRPCClient {
+		ps.Imports = append(ps.Imports, descriptor.GoPackage{Path: "google.golang.org/grpc/metadata"})
+	}
+	ps.Imports = append(ps.Imports, imports.list()...)
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := adaptersTemplate.Execute(w, tp); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func applyFakeTemplate(ps params) (string, error) {
+	imports := make(goImports)
+	tp := serviceParams{
+		Services:    gatewayServices(ps.Files, imports),
+		PackageName: ps.PackageName,
+	}
+
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "context"},
+		{Path: "sync"},
+		{Path: "google.golang.org/protobuf/proto"},
+		{Path: ps.ModuleName + "/" + ps.PackageName},
+	}
+	ps.Imports = append(ps.Imports, imports.list()...)
+	ps.PackageName = "fake"
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := fakeTemplate.Execute(w, tp); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+// routeTest is a generated test of the route serving a binding.
+type routeTest struct {
+	*bindingSample
+	Binding *descriptor.Binding
+	// StreamsResponse reports whether the method responds with a google.api.HttpBody, whose body is streamed by the test.
+	StreamsResponse bool
+	// RequestType and ResponseType are the go types of the messages of the method, if the test sets its function
+	// of the fake. They are empty otherwise.
+	RequestType, ResponseType string
+}
+
+type testsParams struct {
+	Tests        []routeTest
+	ErrorEncoder string
+	PackageName  string
+	// NegotiateAccept is whether the handlers reject the requests with an unacceptable Accept header.
+	NegotiateAccept bool
+	// Validation is the test the field violations of gen.Validator are tested with, if the requests are validated.
+	Validation *routeTest
+	// Metadata is the test the ServerMetadata written to the responses is tested with.
+	Metadata *routeTest
+}
+
+func applyTestsTemplate(reg *descriptor.Registry, ps params) (string, error) {
+	s := newSampler(reg)
+	tp := testsParams{
+		ErrorEncoder:    ps.ErrorEncoder,
+		PackageName:     ps.PackageName,
+		NegotiateAccept: ps.NegotiateAccept,
+	}
+	var hasBody, hasOutputOnly, hasStreamed bool
+	for _, f := range ps.Files {
+		for _, svc := range f.Services {
+			for _, m := range svc.Methods {
+				for _, b := range m.Bindings {
+					sample, err := s.sample(b)
+					if err != nil {
+						return "", fmt.Errorf("failed to synthesize a request for %s: %v", m.FQMN(), err)
+					}
+					hasBody = hasBody || sample.Body != ""
+					hasOutputOnly = hasOutputOnly || sample.OutputOnlyBody != ""
+					t := routeTest{
+						bindingSample: sample,
+						Binding:       b,
+					}
+					if sample.Skip == "" && (tp.Metadata == nil || m.ResponseType.FQMN() == httpBodyType) {
+						t.RequestType, t.ResponseType = s.goType(m.RequestType), s.goType(m.ResponseType)
+						t.StreamsResponse = m.ResponseType.FQMN() == httpBodyType
+						hasStreamed = hasStreamed || t.StreamsResponse
+					}
+					tp.Tests = append(tp.Tests, t)
+					if ps.Validate && ps.ErrorEncoder == "" && tp.Validation == nil && sample.Skip == "" {
+						validation := tp.Tests[len(tp.Tests)-1]
+						tp.Validation = &validation
+					}
+					if tp.Metadata == nil && sample.Skip == "" {
+						metadata := tp.Tests[len(tp.Tests)-1]
+						tp.Metadata = &metadata
+					}
+				}
+			}
+		}
+	}
+
+	w := bytes.NewBuffer(nil)
+	ps.Imports = []descriptor.GoPackage{
+		{Path: "net/http"},
+		{Path: "net/http/httptest"},
+		{Path: "sync"},
+		{Path: "testing"},
+		{Path: "google.golang.org/protobuf/proto"},
+		{Path: ps.ModuleName + "/" + ps.PackageName},
+		{Path: ps.ModuleName + "/" + ps.PackageName + "/fake"},
+		{Path: ps.ModuleName + "/" + ps.PackageName + "/muxkit"},
+	}
+	if hasBody || hasStreamed || tp.Validation != nil {
+		ps.Imports = append(ps.Imports, descriptor.GoPackage{Path: "strings"})
+	}
+	if hasStreamed || tp.Metadata != nil {
+		ps.Imports = append(ps.Imports, descriptor.GoPackage{Path: "context"})
+	}
+	if tp.Validation != nil {
+		ps.Imports = append(ps.Imports,
+			descriptor.GoPackage{Path: "fmt"},
+			descriptor.GoPackage{Path: "reflect"},
+			descriptor.GoPackage{Path: "google.golang.org/genproto/googleapis/rpc/errdetails"},
+			descriptor.GoPackage{Path: "google.golang.org/genproto/googleapis/rpc/status", Alias: "spb"},
+			descriptor.GoPackage{Path: "google.golang.org/grpc/status"},
+			descriptor.GoPackage{Path: "google.golang.org/protobuf/encoding/protojson"},
+			descriptor.GoPackage{Path: "google.golang.org/protobuf/encoding/prototext"},
+			descriptor.GoPackage{Path: "google.golang.org/protobuf/reflect/protodesc"},
+			descriptor.GoPackage{Path: "google.golang.org/protobuf/types/descriptorpb"},
+			descriptor.GoPackage{Path: "google.golang.org/protobuf/types/dynamicpb"},
+		)
+	}
+	if hasOutputOnly {
+		ps.Imports = append(ps.Imports, descriptor.GoPackage{Path: "io"})
+	}
+	ps.Imports = append(ps.Imports, s.list()...)
+	ps.PackageName = ps.PackageName + "_test"
+	if err := serviceHeaderTemplate.Execute(w, ps); err != nil {
+		return "", err
+	}
+	if err := testsTemplate.Execute(w, tp); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func readModuleName() string {
+	file, err := os.Open("go.mod")
+	if err != nil {
+		panic(err)
+	}
+	r := bufio.NewReader(file)
+	line, _, err := r.ReadLine()
+	if err != nil {
+		panic(err)
+	}
+	moduleName := bytes.TrimPrefix(line, []byte("module "))
+	return string(moduleName)
+}
+
+var (
+	funcs = template.FuncMap{
+		"ToLower":            strings.ToLower,
+		"HandlerName":        handlerName,
+		"ServiceName":        serviceName,
+		"PackageRef":         packageRef,
+		"RoutePath":          routePath,
+		"RouteWildcard":      routeWildcard,
+		"HeaderParamDecoder": headerParamDecoder,
+		"UpdateMask":         bindingUpdateMask,
+		"FieldBehaviors":     bindingFieldBehaviors,
+		"DecodeDoc":          decodeDoc,
+		"ResourceNameCheck":  resourceNameCheck,
+		"ResponseStatus":     bindingResponseStatus,
+		"ClientBinding":      newClientBinding,
+		"NegotiatesResponse": negotiatesResponse,
+		"RequestDecoder":     requestDecoder,
+		"HasQueryParam": func(b *descriptor.Binding) bool {
+			return binding{Binding: b}.HasQueryParam()
+		},
+		"QueryParamFilter": func(b *descriptor.Binding) queryParamFilter {
+			return binding{Binding: b}.QueryParamFilter()
+		},
+	}
+
+	kitHeaderTemplate = template.Must(template.New("header").Parse(`
+// Code generated by protoc-gen-gokitmux. DO NOT EDIT.
+// source: {{.GetName}}
+
+/*
+Package {{.GoPkg.Name}} is a reverse proxy.
+
+It translates gRPC into RESTful JSON APIs.
+*/
+package {{.GoPkg.Name | printf "%s\n"}}
+
+import (
+	{{range $i := .Imports}}{{if $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+
+	{{range $i := .Imports}}{{if not $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+)
+`))
+
+	serviceHeaderTemplate = template.Must(template.New("header").Parse(`
+// Code generated by protoc-gen-gokitmux. DO NOT EDIT.
+
+package {{.PackageName | printf "%s\n"}}
+import (
+	{{range $i := .Imports}}{{if $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+
+	{{range $i := .Imports}}{{if not $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+)
+`))
+
+	muxkitHeaderTemplate = template.Must(template.New("header").Parse(`
+// Code generated by protoc-gen-gokitmux. DO NOT EDIT.
+
+package muxkit
+
+import (
+	{{range $i := .Imports}}{{if $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+
+	{{range $i := .Imports}}{{if not $i.Standard}}{{$i | printf "%s\n"}}{{end}}{{end}}
+)
+`))
+
+	kitTemplate = template.Must(template.New("kit").Funcs(funcs).Parse(`
+{{$ErrorEncoder := .ErrorEncoder}}
+{{$PackageName := .PackageName}}
+func New() {
+	{{range $i, $svc := .Services}}
+	{{range $j, $m := $svc.Methods}}
+	{{range $k, $b := $m.Bindings}}
+	h{{$i}}{{$j}}{{$k}} := &{{HandlerName $b}}{}
+	{{if $.SplitServices}}{{$PackageName}}.Register{{ServiceName $svc}}Handler(h{{$i}}{{$j}}{{$k}}){{else}}{{$PackageName}}.RegisterHandler(h{{$i}}{{$j}}{{$k}}){{end}}
+	{{end}}
+	{{end}}
+	{{end}}
+}
+{{range $svc := .Services}}
+	{{range $m := $svc.Methods}}
+	{{range $b := $m.Bindings}}
+	type {{HandlerName $b}} struct{}
+	{{- if HasQueryParam $b}}
+
+	// filter_{{HandlerName $b}} are the field paths {{HandlerName $b}} binds to the path and the body,
+	// which DecodeQuery leaves out of the query parameters.
+	var filter_{{HandlerName $b}} = {{QueryParamFilter $b}}
+	{{- end}}
+	{{end}}
+	{{end}}
+{{end}}
+{{range $svc := .Services}}
+	{{range $m := $svc.Methods}}
+	{{range $b := $m.Bindings}}
+	{{- $service := "GatewayService"}}{{if $.SplitServices}}{{$service = ServiceName $svc}}{{end}}
+	{{- if eq $.Transport "nethttp"}}
+	func (e *{{HandlerName $b}}) Register(svc {{$PackageName}}.{{$service}}) *{{$PackageName}}.Route {
+	{{- else}}
+	func (e *{{HandlerName $b}}) Register(svc {{$PackageName}}.{{$service}}, endpoints *{{$PackageName}}.Endpoints) *{{$PackageName}}.Route {
+	{{- end}}
+		{{- if eq $.Transport "nethttp"}}
+		{{$m.GetName}}{{$.RegisterFuncSuffix}} := {{$PackageName}}.NewHandler(
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return svc.{{$m.GetName}}(ctx, req.(*{{$m.RequestType.GoType ""}}))
+			},
+			{{RequestDecoder $PackageName $b $.Validate $.RejectImmutable $.NegotiateAccept}},
+			{{- with $s := ResponseStatus $.SuccessStatus $b}}
+			{{$PackageName}}.EncodeResponseWithStatus(e.Encode, {{$PackageName}}.ResponseStatus{
+				Code: {{$s.Code}},
+				{{- if $s.Location}}
+				Location: func(response interface{}) string {
+					return response.(*{{$m.ResponseType.GoType ""}}).Get{{$s.Location}}()
+				},
+				{{- end}}
+			}),
+			{{- else}}
+			{{$PackageName}}.EncodeResponse(e.Encode),
+			{{- end}}
+			{{if $ErrorEncoder}}{{$ErrorEncoder}}{{else}}nil{{end}},
+		)
+		{{- else}}
+		ep := endpoints.Set({{$svc.FQSN | printf "%q"}}, func() interface{} {
+			return Make{{$svc.GetName}}ServerEndpoints(svc)
+		}).({{$svc.GetName}}Set).{{$m.GetName}}Endpoint
+		if m, ok := interface{}(e).({{$PackageName}}.{{if $.SplitServices}}{{$service}}{{end}}Maker); ok {
+			ep = m.Make(svc)
+		}
+		{{$m.GetName}}{{$.RegisterFuncSuffix}} := httptransport.NewServer(
+			endpoints.Wrap(ep),
+			{{RequestDecoder $PackageName $b $.Validate $.RejectImmutable $.NegotiateAccept}},
+			{{- with $s := ResponseStatus $.SuccessStatus $b}}
+			{{$PackageName}}.EncodeResponseWithStatus(e.Encode, {{$PackageName}}.ResponseStatus{
+				Code: {{$s.Code}},
+				{{- if $s.Location}}
+				Location: func(response interface{}) string {
+					return response.(*{{$m.ResponseType.GoType ""}}).Get{{$s.Location}}()
+				},
+				{{- end}}
+			}),
+			{{- else}}
+			{{$PackageName}}.EncodeResponse(e.Encode),
+			{{- end}}
+			httptransport.ServerBefore({{$PackageName}}.PopulateContext),
+			httptransport.ServerAfter({{$PackageName}}.WriteServerMetadata),
+			{{if $ErrorEncoder}}httptransport.ServerErrorEncoder({{$ErrorEncoder}}),{{end}}
+		)
+		{{- end}}
+		{{if $.Metrics}}
+		{{$svc.GetName}}{{$.RegisterFuncSuffix}}Client := metrics.ForHandler(
+			e.ForHandler({{$m.GetName}}{{$.RegisterFuncSuffix}}),
+			"{{$m.GetName}}",
+		)
+		{{else}}
+		{{$svc.GetName}}{{$.RegisterFuncSuffix}}Client := e.ForHandler({{$m.GetName}}{{$.RegisterFuncSuffix}})
+		{{end}}
+
+		r := &{{$PackageName}}.Route{
+			Path: {{RoutePath $.Router $b | printf "%q"}},
+			Handler: {{$svc.GetName}}{{$.RegisterFuncSuffix}}Client,
+			Method: {{$b.HTTPMethod | printf "%q"}},
+			{{with $n := HandlerName $b }}Name: {{ ToLower $n | printf "%q"}},{{end}}
+			{{- with RouteWildcard $.Router $b}}
+			Wildcard: {{printf "%q" .}},
+			{{- end}}
+		}
+
+		return r
+	}
+	{{$mask := UpdateMask $b}}
+	{{- with $doc := DecodeDoc $b $.Validate $.RejectImmutable}}
+	// {{$doc}}
+	func (e *{{HandlerName $b}}) decode(ctx context.Context, r *http.Request) (interface{}, error) {
+		{{- range $p := $b.PathParams}}
+		{{- with ResourceNameCheck $PackageName $p}}
+		{{.}}
+		{{- end}}
+		{{- end}}
+		{{- if $mask}}
+		body := {{$PackageName}}.RecordBody(r)
+		{{- end}}
+		request, err := e.Decode(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		req := request.(*{{$m.RequestType.GoType ""}})
+		{{- range $p := $b.HeaderParams}}
+		{{HeaderParamDecoder $PackageName $p}}
+		{{- end}}
+		{{- with $mask}}
+		if req.{{.Field}} == nil {
+			mask, err := {{$PackageName}}.UpdateMask(r, body.Bytes(), req.{{.Body}}{{with FieldBehaviors $b $.RejectImmutable}}{{range .Unmasked}}, {{printf "%q" .}}{{end}}{{end}})
+			if err != nil {
+				return nil, err
+			}
+			req.{{.Field}} = mask
+		}
+		{{- end}}
+		{{- with $fb := FieldBehaviors $b $.RejectImmutable}}
+		{{- if $fb.OutputOnly}}
+		{{$PackageName}}.ClearFields(req{{range $fb.OutputOnly}}, {{printf "%q" .}}{{end}})
+		{{- end}}
+		{{- if $fb.Required}}
+		if err := {{$PackageName}}.RequireFields(req{{range $fb.Required}}, {{printf "%q" .}}{{end}}); err != nil {
+			return nil, err
+		}
+		{{- end}}
+		{{- if $fb.Immutable}}
+		if err := {{$PackageName}}.RejectMasked(req.{{$mask.Field}}{{range $fb.Immutable}}, {{printf "%q" .}}{{end}}); err != nil {
+			return nil, err
+		}
+		{{- end}}
+		{{- end}}
+		{{- if $.Validate}}
+		if err := {{$PackageName}}.ValidateRequest(req); err != nil {
+			return nil, err
+		}
+		{{- end}}
+		return req, nil
+	}
+	{{end}}
+	{{end}}
+	{{end}}
+{{end}}
+{{range $svc := .Sets}}
+{{$service := "GatewayService"}}{{if $.SplitServices}}{{$service = ServiceName $svc.Service}}{{end}}
+// {{$svc.GetName}}Set collects the endpoints of the {{$svc.GetName}} service, so that middleware,
+// transports and clients share them.
+// It implements the service by calling its endpoints, e.g. client endpoints.
+type {{$svc.GetName}}Set struct {
+	{{- if $.RequireUnimplemented}}
+	{{$PackageName}}.Unimplemented{{$service}}
+	{{end}}
+	{{- range $m := $svc.Methods}}
+	{{$m.GetName}}Endpoint endpoint.Endpoint
+	{{- end}}
+}
+{{if $.SplitServices}}
+var _ {{$PackageName}}.{{$service}} = {{$svc.GetName}}Set{}
+{{end}}
+// Make{{$svc.GetName}}ServerEndpoints returns a {{$svc.GetName}}Set whose endpoints call "svc".
+func Make{{$svc.GetName}}ServerEndpoints(svc {{$PackageName}}.{{$service}}) {{$svc.GetName}}Set {
+	return {{$svc.GetName}}Set{
+		{{- range $m := $svc.Methods}}
+		{{$m.GetName}}Endpoint: Make{{$m.GetName}}Endpoint(svc),
+		{{- end}}
+	}
+}
+{{range $m := $svc.Methods}}
+// Make{{$m.GetName}}Endpoint returns an endpoint calling {{$m.GetName}} of "svc".
+func Make{{$m.GetName}}Endpoint(svc {{$PackageName}}.{{$service}}) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return svc.{{$m.GetName}}(ctx, request.(*{{$m.RequestType}}))
+	}
+}
+
+// {{$m.GetName}} calls {{$m.GetName}}Endpoint.
+func (s {{$svc.GetName}}Set) {{$m.GetName}}(ctx context.Context, req *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	resp, err := s.{{$m.GetName}}Endpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*{{$m.ResponseType}}), nil
+}
+{{end}}
+// Make{{$svc.GetName}}ClientEndpoints returns a {{$svc.GetName}}Set whose endpoints call the gateway at "instance",
+// e.g. "https://example.com", with the first binding of each method.
+func Make{{$svc.GetName}}ClientEndpoints(instance string, options ...httptransport.ClientOption) ({{$svc.GetName}}Set, error) {
+	tgt, err := {{$PackageName}}.ClientTarget(instance)
+	if err != nil {
+		return {{$svc.GetName}}Set{}, err
+	}
+	return {{$svc.GetName}}Set{
+		{{- range $m := $svc.Methods}}
+		{{$m.GetName}}Endpoint: httptransport.NewClient({{(ClientBinding $m.Method).HTTPMethod | printf "%q"}}, tgt, encode{{$m.GetName}}Request, decode{{$m.GetName}}Response, options...).Endpoint(),
+		{{- end}}
+	}, nil
+}
+{{range $m := $svc.Methods}}
+{{- $c := ClientBinding $m.Method}}
+// encode{{$m.GetName}}Request encodes the request of {{$m.GetName}} as {{$c.HTTPMethod}} {{$c.Path}} binds it.
+func encode{{$m.GetName}}Request(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(*{{$m.RequestType}})
+	if err := {{$PackageName}}.EncodePath(r, req, {{$c.Path | printf "%q"}}); err != nil {
+		return err
+	}
+	{{- range $h := $c.Headers}}
+	if err := {{$PackageName}}.{{if $h.Cookie}}EncodeCookie(r, req, {{$h.Cookie | printf "%q"}}{{else}}EncodeHeader(r, req, {{$h.Header | printf "%q"}}{{end}}, {{$h.Field | printf "%q"}}); err != nil {
+		return err
+	}
+	{{- end}}
+	{{- if not $c.Body}}
+	return {{$PackageName}}.EncodeQuery(r, req{{range $c.Bound}}, {{printf "%q" .}}{{end}})
+	{{- else}}
+	{{- if ne $c.Body "*"}}
+	if err := {{$PackageName}}.EncodeQuery(r, req{{range $c.Bound}}, {{printf "%q" .}}{{end}}); err != nil {
+		return err
+	}
+	{{- end}}
+	return {{$PackageName}}.EncodeBody(r, req, {{$c.Body | printf "%q"}})
+	{{- end}}
+}
+
+// decode{{$m.GetName}}Response decodes the response of {{$m.GetName}}.
+func decode{{$m.GetName}}Response(_ context.Context, resp *http.Response) (interface{}, error) {
+	response := &{{$m.ResponseType}}{}
+	if err := {{$PackageName}}.DecodeResponse(resp, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+{{end}}
+{{end}}`))
+
+	serviceTemplate = template.Must(template.New("service").Funcs(funcs).Parse(`
+{{- if .SplitServices}}
+{{- range $svc := .Services}}
+// {{ServiceName $svc.Service}} is the {{$svc.GetName}} service served by the gateway.
+type {{ServiceName $svc.Service}} interface {
+	{{- range $m := $svc.Methods}}
+	{{$m.GetName}}(context.Context, *{{$m.RequestType}}) (*{{$m.ResponseType}}, error)
+	{{- end}}
+	{{- if $.RequireUnimplemented}}
+	mustEmbedUnimplemented{{ServiceName $svc.Service}}()
+	{{- end}}
+}
+{{end}}
+// GatewayService is the aggregate of the services served by the gateway.
+type GatewayService interface {
+	{{- range $svc := .Services}}
+	{{ServiceName $svc.Service}}
+	{{- end}}
+}
+{{- else}}
+// GatewayService is the service served by the gateway.
+type GatewayService interface {
+	{{- range $m := .Methods}}
+	{{$m.GetName}}(context.Context, *{{$m.RequestType}}) (*{{$m.ResponseType}}, error)
+	{{- end}}
+	{{- if .RequireUnimplemented}}
+	mustEmbedUnimplementedGatewayService()
+	{{- end}}
+}
+{{- end}}`))
+
+	unimplementedTemplate = template.Must(template.New("unimplemented").Funcs(funcs).Parse(`
+// UnimplementedError is returned by the methods of UnimplementedGatewayService.
+// It is encoded as HTTP 501 Not Implemented.
+type UnimplementedError struct {
+	// Method is the name of the unimplemented method.
+	Method string
+}
+
+func (e *UnimplementedError) Error() string {
+	return fmt.Sprintf("method %s not implemented", e.Method)
+}
+
+// StatusCode implements the StatusCoder interface of go-kit.
+func (e *UnimplementedError) StatusCode() int {
+	return http.StatusNotImplemented
+}
+
+{{- if .SplitServices}}
+{{- range $svc := .Services}}
+{{$name := ServiceName $svc.Service}}
+// Unimplemented{{$name}} should be embedded by {{$name}} implementations,
+// so that they keep compiling when methods are added to the service.
+type Unimplemented{{$name}} struct{}
+{{range $m := $svc.Methods}}
+func (Unimplemented{{$name}}) {{$m.GetName}}(context.Context, *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	return nil, &UnimplementedError{Method: {{$m.GetName | printf "%q"}}}
+}
+{{end}}
+{{- if $.RequireUnimplemented}}
+func (Unimplemented{{$name}}) mustEmbedUnimplemented{{$name}}() {}
+{{end}}
+{{- end}}
+// UnimplementedGatewayService should be embedded by GatewayService implementations,
+// so that they keep compiling when methods are added to the gateway.
+type UnimplementedGatewayService struct {
+	{{- range $svc := .Services}}
+	Unimplemented{{ServiceName $svc.Service}}
+	{{- end}}
+}
+{{- else}}
+// UnimplementedGatewayService should be embedded by GatewayService implementations,
+// so that they keep compiling when methods are added to the gateway.
+type UnimplementedGatewayService struct{}
+{{range $m := .Methods}}
+func (UnimplementedGatewayService) {{$m.GetName}}(context.Context, *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	return nil, &UnimplementedError{Method: {{$m.GetName | printf "%q"}}}
+}
+{{end}}
+{{- if .RequireUnimplemented}}
+func (UnimplementedGatewayService) mustEmbedUnimplementedGatewayService() {}
+{{- end}}
+{{- end}}`))
+
+	routesTemplate = template.Must(template.New("kit").Funcs(funcs).Parse(`
+{{- $router := "*mux.Router"}}{{$new := "r := mux.NewRouter()"}}
+{{- if eq .Router "servemux"}}{{$router = "*http.ServeMux"}}{{$new = "r := http.NewServeMux()"}}{{end}}
+{{- if eq .Router "chi"}}{{$router = "chi.Router"}}{{$new = "var r chi.Router = chi.NewRouter()"}}{{end}}
+{{- $handler := "route.Handler"}}{{if .CORS}}{{$handler = "corsHandler(route)"}}{{end}}
+{{- define "unrouted"}}
+	{{- if eq .Router "servemux"}}
+	r.Handle("/", unroutedHandler(r))
+	{{- else if eq .Router "chi"}}
+	r.NotFound(unroutedHandler(r))
+	r.MethodNotAllowed(unroutedHandler(r))
+	{{- else}}
+	r.NotFoundHandler = unroutedHandler(r)
+	r.MethodNotAllowedHandler = unroutedHandler(r)
+	{{- end}}
+{{- end}}
+{{- $gokit := ne .Transport "nethttp"}}
+{{- if .SplitServices}}
+{{- range $svc := .Services}}
+{{- if $gokit}}
+// Register{{ServiceName $svc}}Routes registers the routes of {{ServiceName $svc}} on "r", their endpoints
+// wrapped with "middleware".
+func Register{{ServiceName $svc}}Routes(r {{$router}}, svc {{ServiceName $svc}}, middleware ...endpoint.Middleware) {
+	endpoints := NewEndpoints(middleware...)
+	for _, h := range {{ServiceName $svc}}Handlers {
+		registerRoute(r, h.Register(svc, endpoints))
+	}
+}
+{{- else}}
+// Register{{ServiceName $svc}}Routes registers the routes of {{ServiceName $svc}} on "r".
+func Register{{ServiceName $svc}}Routes(r {{$router}}, svc {{ServiceName $svc}}) {
+	for _, h := range {{ServiceName $svc}}Handlers {
+		registerRoute(r, h.Register(svc))
+	}
+}
+{{- end}}
+{{end}}
+{{- if $gokit}}
+// Router returns the router of the routes of "svc", their endpoints wrapped with "middleware".
+func Router(svc GatewayService, middleware ...endpoint.Middleware) {{$router}} {
+{{- else}}
+func Router(svc GatewayService) {{$router}} {
+{{- end}}
+	{{$new}}
+	{{- if ne .Router "servemux"}}
+	{{template "unrouted" .}}
+	{{- end}}
+	{{range $svc := .Services}}
+	Register{{ServiceName $svc}}Routes(r, svc{{if $gokit}}, middleware...{{end}})
+	{{- end}}
+
+	r = ManualRouter(svc, r)
+	{{- if eq .Router "servemux"}}
+	{{template "unrouted" .}}
+	{{- end}}
+
+	return r
+}
+{{- else}}
+{{- if $gokit}}
+// Router returns the router of the routes of "svc", their endpoints wrapped with "middleware".
+func Router(svc GatewayService, middleware ...endpoint.Middleware) {{$router}} {
+	{{$new}}
+	{{- if ne .Router "servemux"}}
+	{{template "unrouted" .}}
+	{{- end}}
+
+	endpoints := NewEndpoints(middleware...)
+	for _, h := range Handlers {
+		registerRoute(r, h.Register(svc, endpoints))
+	}
+{{- else}}
+func Router(svc GatewayService) {{$router}} {
+	{{$new}}
+	{{- if ne .Router "servemux"}}
+	{{template "unrouted" .}}
+	{{- end}}
+
+	for _, h := range Handlers {
+		registerRoute(r, h.Register(svc))
+	}
+{{- end}}
+
+	r = ManualRouter(svc, r)
+	{{- if eq .Router "servemux"}}
+	{{template "unrouted" .}}
+	{{- end}}
+
+	return r
+}
+{{- end}}
+{{if eq .Router "servemux"}}
+func registerRoute(r *http.ServeMux, route *Route) {
+	r.Handle(route.Method+" "+route.Path, {{$handler}})
+}
+
+// routeKey returns the key of the route "r" routes the path of "req" to for "method", its pattern,
+// or an empty string if there is none. GET patterns route HEAD requests, and "/" is the pattern of
+// unroutedHandler.
+func routeKey(r *http.ServeMux, req *http.Request, method string) string {
+	probe := *req
+	probe.Method = method
+	if _, pattern := r.Handler(&probe); pattern != "/" {
+		return pattern
+	}
+	return ""
+}
+
+// PathParam returns the value of the path variable "name", the field path it is bound to,
+// in the request "r" routed by Router.
+func PathParam(r *http.Request, name string) string {
+	return r.PathValue(strings.Replace(name, ".", "_", -1))
+}
+{{- else if eq .Router "chi"}}
+func registerRoute(r chi.Router, route *Route) {
+	handler := {{$handler}}
+	if route.Wildcard != "" {
+		handler = bindWildcard(route.Wildcard, handler)
+	}
+	r.Method(route.Method, route.Path, handler)
+	if route.Method == http.MethodGet {
+		r.Method(http.MethodHead, route.Path, handler)
+	}
+}
+
+// routeKey returns the key of the route "r" routes the path of "req" to for "method", e.g.
+// "GET /v1/hello/{name}", or an empty string if there is none.
+func routeKey(r chi.Router, req *http.Request, method string) string {
+	path := req.URL.RawPath
+	if path == "" {
+		path = req.URL.Path
+	}
+	rctx := chi.NewRouteContext()
+	if !r.Match(rctx, method, path) {
+		return ""
+	}
+	return method + " " + rctx.RoutePattern()
+}
+
+// bindWildcard returns "h" adding the value of chi's "*" to the path variables as "name", the
+// variable bound to the trailing "*" of its route.
+func bindWildcard(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			rctx.URLParams.Add(name, rctx.URLParam("*"))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// PathParam returns the value of the path variable "name", the field path it is bound to,
+// in the request "r" routed by Router.
+func PathParam(r *http.Request, name string) string {
+	return chi.URLParam(r, name)
+}
+{{- else}}
+func registerRoute(r *mux.Router, route *Route) {
+	methods := []string{route.Method}
+	if route.Method == http.MethodGet {
+		methods = append(methods, http.MethodHead)
+	}
+	muxRoute := r.Handle(route.Path, {{$handler}}).Methods(methods...)
+
+	if route.Name != "" {
+		muxRoute.Name(route.Name)
+	}
+}
+
+// routeKey returns the key of the route "r" routes the path of "req" to for "method", e.g.
+// "GET /v1/hello/{name}", or an empty string if there is none.
+func routeKey(r *mux.Router, req *http.Request, method string) string {
+	probe := *req
+	probe.Method = method
+	var match mux.RouteMatch
+	if !r.Match(&probe, &match) || match.MatchErr != nil {
+		return ""
+	}
+	path, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return method
+	}
+	return method + " " + path
+}
+
+// PathParam returns the value of the path variable "name", the field path it is bound to,
+// in the request "r" routed by Router.
+func PathParam(r *http.Request, name string) string {
+	return mux.Vars(r)[name]
+}
+{{- end}}
+
+// routeMethods are the HTTP methods the methods allowed on the path of a request are looked up among.
+var routeMethods = []string{ {{- range $i, $m := .RouteMethods}}{{if $i}}, {{end}}{{printf "%q" $m}}{{end -}} }
+
+// allowedMethods returns the methods "r" routes the path of "req" for, and OPTIONS if there is any.
+func allowedMethods(r {{$router}}, req *http.Request) []string {
+	var result []string
+	for _, method := range routeMethods {
+		if routeKey(r, req, method) != "" {
+			result = append(result, method)
+		}
+	}
+	if len(result) > 0 {
+		result = append(result, http.MethodOptions)
+	}
+	return result
+}
+
+// unroutedHandler returns the handler of the requests "r" has no route for. OPTIONS requests of a
+// routed path are answered with 204 No Content and an Allow header listing the methods of the path.
+// Other requests are answered with a google.rpc.Status: NOT_FOUND with 404 Not Found for unknown paths,
+// and UNIMPLEMENTED with 405 Method Not Allowed and the Allow header for the methods of a routed path.
+func unroutedHandler(r {{$router}}) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		allowed := allowedMethods(r, req)
+		if len(allowed) == 0 {
+			writeRoutingStatus(w, http.StatusNotFound, status.Newf(codes.NotFound, "no route for %s", req.URL.Path))
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		if req.Method == http.MethodOptions {
+			{{- if .CORS}}
+			if method := req.Header.Get("Access-Control-Request-Method"); method != "" {
+				if key := routeKey(r, req, method); key != "" {
+					writePreflight(w, req, corsPolicy(key), allowed)
+				}
+			}
+			{{- end}}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeRoutingStatus(w, http.StatusMethodNotAllowed, status.Newf(codes.Unimplemented, "method %s is not allowed for %s", req.Method, req.URL.Path))
+	}
+}
+
+// writeRoutingStatus writes "st" as JSON with the status code "code".
+func writeRoutingStatus(w http.ResponseWriter, code int, st *status.Status) {
+	body, err := protojson.Marshal(st.Proto())
+	if err != nil {
+		http.Error(w, st.Message(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}`))
 
 	muxkitTemplate = template.Must(template.New("kit").Funcs(funcs).Parse(`
 func InitAll() {
@@ -426,25 +1699,3047 @@ func InitAll() {
 	{{$f.Package}}.New(){{end}}
 }`))
 
+	testsTemplate = template.Must(template.New("tests").Funcs(funcs).Parse(`
+{{$PackageName := .PackageName}}
+var initHandlers sync.Once
+
+// newTestRouter returns the router of the gateway serving "svc".
+func newTestRouter(svc {{$PackageName}}.GatewayService) http.Handler {
+	initHandlers.Do(muxkit.InitAll)
+	return {{$PackageName}}.Router(svc)
+}
+
+// statusError is an error carrying the HTTP status code it should be encoded with.
+type statusError int
+
+func (e statusError) Error() string { return http.StatusText(int(e)) }
+
+func (e statusError) StatusCode() int { return int(e) }
+
+{{range $t := .Tests}}
+{{$name := HandlerName $t.Binding}}
+func Test{{$name}}(t *testing.T) {
+	{{- if $t.Skip}}
+	t.Skip({{$t.Skip | printf "%q"}})
+	{{- else}}
+	newRequest := func() *http.Request {
+		{{- if $t.Body}}
+		r := httptest.NewRequest({{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, strings.NewReader({{$t.Body | printf "%q"}}))
+		r.Header.Set("Content-Type", {{$t.ContentType | printf "%q"}})
+		{{- else if $t.Headers}}
+		r := httptest.NewRequest({{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, nil)
+		{{- else}}
+		return httptest.NewRequest({{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, nil)
+		{{- end}}
+		{{- range $h := $t.Headers}}
+		{{- if $h.Header}}
+		r.Header.Set({{$h.Header | printf "%q"}}, {{$h.Value | printf "%q"}})
+		{{- else}}
+		r.AddCookie(&http.Cookie{Name: {{$h.Cookie | printf "%q"}}, Value: {{$h.Value | printf "%q"}}})
+		{{- end}}
+		{{- end}}
+		{{- if or $t.Body $t.Headers}}
+		return r
+		{{- end}}
+	}
+
+	t.Run("request", func(t *testing.T) {
+		svc := &fake.GatewayService{}
+		w := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(w, newRequest())
+
+		if w.Code < 200 || w.Code >= 300 {
+			t.Fatalf("%s %s responded %d; want success: %s", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, w.Code, w.Body)
+		}
+		calls := svc.{{$t.Binding.Method.GetName}}Calls()
+		if len(calls) != 1 {
+			t.Fatalf("{{$t.Binding.Method.GetName}} was called %d times; want 1", len(calls))
+		}
+		got, want := calls[0], {{$t.Want}}
+		if !proto.Equal(got, want) {
+			t.Errorf("{{$t.Binding.Method.GetName}} received %v; want %v", got, want)
+		}
+	})
+	{{- with $t.Binding.PathParams}}
+	{{- $p := index . 0}}
+
+	t.Run("query naming a path field", func(t *testing.T) {
+		svc := &fake.GatewayService{}
+		r := newRequest()
+		query := r.URL.Query()
+		query.Set({{$p.FieldPath.String | printf "%q"}}, "query-value")
+		r.URL.RawQuery = query.Encode()
+		w := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(w, r)
+
+		if w.Code < 200 || w.Code >= 300 {
+			t.Fatalf("%s %s responded %d; want success: %s", {{$t.Method | printf "%q"}}, r.URL, w.Code, w.Body)
+		}
+		calls := svc.{{$t.Binding.Method.GetName}}Calls()
+		if len(calls) != 1 {
+			t.Fatalf("{{$t.Binding.Method.GetName}} was called %d times; want 1", len(calls))
+		}
+		got, want := calls[0], {{$t.Want}}
+		if !proto.Equal(got, want) {
+			t.Errorf("{{$t.Binding.Method.GetName}} received %v; want %v", got, want)
+		}
+	})
+	{{- end}}
+	{{- if $t.StreamsResponse}}
+
+	t.Run("streamed response", func(t *testing.T) {
+		data := strings.Repeat("x", int({{$PackageName}}.HttpBodyBufferLimit)+1)
+		svc := &fake.GatewayService{
+			{{$t.Binding.Method.GetName}}Func: func(ctx context.Context, _ *{{$t.RequestType}}) (*{{$t.ResponseType}}, error) {
+				if err := {{$PackageName}}.SetResponseBody(ctx, strings.NewReader(data)); err != nil {
+					return nil, err
+				}
+				return &{{$t.ResponseType}}{ContentType: "text/plain"}, nil
+			},
+		}
+		w := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(w, newRequest())
+
+		if w.Code < 200 || w.Code >= 300 {
+			t.Fatalf("%s %s responded %d; want success", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, w.Code)
+		}
+		if got, want := w.Header().Get("Content-Type"), "text/plain"; got != want {
+			t.Errorf("%s %s responded with Content-Type %q; want %q", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, want)
+		}
+		if got := w.Body.String(); got != data {
+			t.Errorf("%s %s responded %d bytes; want %d", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, len(got), len(data))
+		}
+	})
+	{{- end}}
+	{{- if not $.ErrorEncoder}}
+
+	t.Run("error", func(t *testing.T) {
+		svc := &fake.GatewayService{DefaultErr: statusError(http.StatusTeapot)}
+		w := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(w, newRequest())
+
+		if got, want := w.Code, http.StatusTeapot; got != want {
+			t.Errorf("%s %s responded %d; want %d", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, want)
+		}
+	})
+	{{- if $t.OutputOnlyBody}}
+
+	t.Run("output only fields", func(t *testing.T) {
+		svc := &fake.GatewayService{}
+		r := newRequest()
+		r.Body = io.NopCloser(strings.NewReader({{$t.OutputOnlyBody | printf "%q"}}))
+		r.ContentLength = int64(len({{$t.OutputOnlyBody | printf "%q"}}))
+		w := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(w, r)
+
+		if w.Code < 200 || w.Code >= 300 {
+			t.Fatalf("%s %s responded %d; want success: %s", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, w.Code, w.Body)
+		}
+		calls := svc.{{$t.Binding.Method.GetName}}Calls()
+		if len(calls) != 1 {
+			t.Fatalf("{{$t.Binding.Method.GetName}} was called %d times; want 1", len(calls))
+		}
+		got, want := calls[0], {{$t.Want}}
+		if !proto.Equal(got, want) {
+			t.Errorf("{{$t.Binding.Method.GetName}} received %v; want %v", got, want)
+		}
+	})
+	{{- end}}
+	{{- if and $.NegotiateAccept (NegotiatesResponse $t.Binding.Method)}}
+
+	t.Run("not acceptable", func(t *testing.T) {
+		svc := &fake.GatewayService{}
+		r := newRequest()
+		r.Header.Set("Accept", "text/x-unacceptable")
+		w := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(w, r)
+
+		if got, want := w.Code, http.StatusNotAcceptable; got != want {
+			t.Errorf("%s %s responded %d; want %d", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, want)
+		}
+		if n := len(svc.{{$t.Binding.Method.GetName}}Calls()); n != 0 {
+			t.Errorf("{{$t.Binding.Method.GetName}} was called %d times; want 0", n)
+		}
+	})
+	{{- end}}
+	{{- range $h := $t.Headers}}
+	{{- if $h.Required}}
+
+	t.Run({{if $h.Header}}{{printf "missing header %s" $h.Header | printf "%q"}}{{else}}{{printf "missing cookie %s" $h.Cookie | printf "%q"}}{{end}}, func(t *testing.T) {
+		svc := &fake.GatewayService{}
+		r := newRequest()
+		{{- if $h.Header}}
+		r.Header.Del({{$h.Header | printf "%q"}})
+		{{- else}}
+		cookies := r.Cookies()
+		r.Header.Del("Cookie")
+		for _, c := range cookies {
+			if c.Name != {{$h.Cookie | printf "%q"}} {
+				r.AddCookie(c)
+			}
+		}
+		{{- end}}
+		w := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(w, r)
+
+		if got, want := w.Code, http.StatusBadRequest; got != want {
+			t.Errorf("%s %s responded %d; want %d", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, want)
+		}
+		if n := len(svc.{{$t.Binding.Method.GetName}}Calls()); n != 0 {
+			t.Errorf("{{$t.Binding.Method.GetName}} was called %d times; want 0", n)
+		}
+	})
+	{{- end}}
+	{{- end}}
+	{{- end}}
+	{{- end}}
+}
+{{end}}
+{{- with $t := .Validation}}
+
+// fieldViolation is a violation reported by a validator other than protoc-gen-validate.
+type fieldViolation struct {
+	field, description string
+}
+
+func (v fieldViolation) Error() string { return v.description }
+
+func (v fieldViolation) Field() string { return v.field }
+
+func (v fieldViolation) Description() string { return v.description }
+
+// violations are the violations of a request, joined as errors.Join does.
+type violations []error
+
+func (v violations) Error() string { return fmt.Sprintf("%d violations", len(v)) }
+
+func (v violations) Unwrap() []error { return v }
+
+// violationsFile declares the messages of buf/validate/validate.proto the errors of protovalidate
+// report their violations with.
+const violationsFile = ` + "`" + `
+name: "buf/validate/violations.proto"
+package: "buf.validate"
+message_type: {
+	name: "Violations"
+	field: { name: "violations" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".buf.validate.Violation" }
+}
+message_type: {
+	name: "Violation"
+	field: { name: "field_path" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+	field: { name: "message" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING }
+	field: { name: "field" number: 5 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".buf.validate.FieldPath" }
+}
+message_type: {
+	name: "FieldPath"
+	field: { name: "elements" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".buf.validate.FieldPathElement" }
+}
+message_type: {
+	name: "FieldPathElement"
+	field: { name: "field_name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING }
+	field: { name: "index" number: 6 label: LABEL_OPTIONAL type: TYPE_UINT64 oneof_index: 0 }
+	field: { name: "string_key" number: 10 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 }
+	oneof_decl: { name: "subscript" }
+}
+` + "`" + `
+
+// validationError is an error of protovalidate, whose ToProto method returns its buf.validate.Violations.
+type validationError struct {
+	violations proto.Message
+}
+
+func (e *validationError) Error() string { return "validation error" }
+
+func (e *validationError) ToProto() proto.Message { return e.violations }
+
+// newValidationError returns a *validationError with the buf.validate.Violations "violations", in text format.
+func newValidationError(t *testing.T, violations string) error {
+	file := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(violationsFile), file); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := dynamicpb.NewMessage(fd.Messages().ByName("Violations"))
+	if err := prototext.Unmarshal([]byte(violations), msg); err != nil {
+		t.Fatal(err)
+	}
+	return &validationError{violations: msg}
+}
+
+func TestValidator(t *testing.T) {
+	for _, spec := range []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{
+			name: "joined errors",
+			err:  violations{fieldViolation{"name", "is required"}, fieldViolation{"display_name", "is too long"}},
+			want: []string{"name: is required", "display_name: is too long"},
+		},
+		{
+			name: "protovalidate",
+			err: newValidationError(t, ` + "`" + `
+				violations: { field: { elements: { field_name: "authors" index: 1 } elements: { field_name: "name" } } message: "is required" }
+				violations: { field: { elements: { field_name: "labels" string_key: "color" } } message: "is too long" }
+				violations: { field_path: "display_name" message: "is too long" }
+				violations: { message: "is not valid" }` + "`" + `),
+			want: []string{"authors[1].name: is required", ` + "`" + `labels["color"]: is too long` + "`" + `, "display_name: is too long", ": is not valid"},
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			defer func(v func(proto.Message) error) { {{$PackageName}}.Validator = v }({{$PackageName}}.Validator)
+			{{$PackageName}}.Validator = func(proto.Message) error {
+				return spec.err
+			}
+			svc := &fake.GatewayService{}
+			{{- template "testRequest" $t}}
+			w := httptest.NewRecorder()
+			newTestRouter(svc).ServeHTTP(w, r)
+
+			if got, want := w.Code, http.StatusBadRequest; got != want {
+				t.Errorf("%s %s responded %d; want %d", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, want)
+			}
+			if got, want := w.Header().Get("Content-Type"), "application/json"; !strings.HasPrefix(got, want) {
+				t.Errorf("%s %s responded with the Content-Type %q; want %q", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, want)
+			}
+			if got := responseViolations(t, w); !reflect.DeepEqual(got, spec.want) {
+				t.Errorf("%s %s responded the violations %q; want %q", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, spec.want)
+			}
+			if n := len(svc.{{$t.Binding.Method.GetName}}Calls()); n != 0 {
+				t.Errorf("{{$t.Binding.Method.GetName}} was called %d times; want 0", n)
+			}
+		})
+	}
+}
+
+// responseViolations returns the field violations of the google.rpc.BadRequest detail of the
+// google.rpc.Status "w" responded, as "field: description".
+func responseViolations(t *testing.T, w *httptest.ResponseRecorder) []string {
+	st := &spb.Status{}
+	if err := protojson.Unmarshal(w.Body.Bytes(), st); err != nil {
+		t.Fatalf("the response %q is not a google.rpc.Status: %v", w.Body, err)
+	}
+	var result []string
+	for _, detail := range status.FromProto(st).Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				result = append(result, v.GetField()+": "+v.GetDescription())
+			}
+		}
+	}
+	return result
+}
+{{- end}}
+{{- with $t := .Metadata}}
+
+// TestServerMetadata checks that the headers set by the services are written to the successful
+// responses only, as go-kit's ServerAfter functions do.
+func TestServerMetadata(t *testing.T) {
+	for _, spec := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "success", want: "value"},
+		{name: "error", err: statusError(http.StatusTeapot)},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			svc := &fake.GatewayService{
+				{{$t.Binding.Method.GetName}}Func: func(ctx context.Context, _ *{{$t.RequestType}}) (*{{$t.ResponseType}}, error) {
+					if err := {{$PackageName}}.SetHeader(ctx, http.Header{"X-Test-Metadata": {"value"}}); err != nil {
+						return nil, err
+					}
+					if spec.err != nil {
+						return nil, spec.err
+					}
+					return &{{$t.ResponseType}}{}, nil
+				},
+			}
+			{{- template "testRequest" $t}}
+			w := httptest.NewRecorder()
+			newTestRouter(svc).ServeHTTP(w, r)
+
+			if got := w.Header().Get("X-Test-Metadata"); got != spec.want {
+				t.Errorf("%s %s responded with X-Test-Metadata %q; want %q", {{$t.Method | printf "%q"}}, {{$t.URL | printf "%q"}}, got, spec.want)
+			}
+		})
+	}
+}
+{{- end}}
+{{- define "testRequest"}}
+			{{- if .Body}}
+			r := httptest.NewRequest({{.Method | printf "%q"}}, {{.URL | printf "%q"}}, strings.NewReader({{.Body | printf "%q"}}))
+			r.Header.Set("Content-Type", {{.ContentType | printf "%q"}})
+			{{- else}}
+			r := httptest.NewRequest({{.Method | printf "%q"}}, {{.URL | printf "%q"}}, nil)
+			{{- end}}
+			{{- range $h := .Headers}}
+			{{- if $h.Header}}
+			r.Header.Set({{$h.Header | printf "%q"}}, {{$h.Value | printf "%q"}})
+			{{- else}}
+			r.AddCookie(&http.Cookie{Name: {{$h.Cookie | printf "%q"}}, Value: {{$h.Value | printf "%q"}}})
+			{{- end}}
+			{{- end}}
+{{- end}}`))
+
+	metadataTemplate = template.Must(template.New("metadata").Parse(`
+const (
+	// MetadataHeaderPrefix prefixes the HTTP headers carrying gRPC metadata.
+	MetadataHeaderPrefix = "Grpc-Metadata-"
+	// MetadataTrailerPrefix prefixes the HTTP trailers carrying gRPC trailers.
+	MetadataTrailerPrefix = "Grpc-Trailer-"
+)
+
+// HeaderMatcher returns the gRPC metadata key an HTTP request header is sent as,
+// and whether it is sent at all.
+type HeaderMatcher func(key string) (string, bool)
+
+// IncomingHeaderMatcher selects the headers of the HTTP requests put into the incoming metadata
+// of their context, see metadata.FromIncomingContext. Replace it before serving requests,
+// e.g. with AllowHeaders, to pass other headers.
+var IncomingHeaderMatcher HeaderMatcher = DefaultHeaderMatcher
+
+// DefaultHeaderMatcher sends the Authorization header and the headers prefixed with MetadataHeaderPrefix,
+// without the prefix.
+func DefaultHeaderMatcher(key string) (string, bool) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	switch {
+	case key == "Authorization":
+		return "authorization", true
+	case strings.HasPrefix(key, MetadataHeaderPrefix):
+		return strings.ToLower(strings.TrimPrefix(key, MetadataHeaderPrefix)), true
+	}
+	return "", false
+}
+
+// AllowHeaders returns a HeaderMatcher sending the headers "keys", lowercased, besides the ones
+// DefaultHeaderMatcher sends.
+func AllowHeaders(keys ...string) HeaderMatcher {
+	allowed := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		allowed[textproto.CanonicalMIMEHeaderKey(k)] = true
+	}
+	return func(key string) (string, bool) {
+		if k, ok := DefaultHeaderMatcher(key); ok {
+			return k, true
+		}
+		if allowed[textproto.CanonicalMIMEHeaderKey(key)] {
+			return strings.ToLower(key), true
+		}
+		return "", false
+	}
+}
+
+// RequestMetadata returns the headers of the HTTP request being served selected by "matcher".
+func RequestMetadata(ctx context.Context, matcher HeaderMatcher) metadata.MD {
+	md := make(metadata.MD)
+	for key, values := range RequestHeader(ctx) {
+		if k, ok := matcher(key); ok {
+			md.Append(k, values...)
+		}
+	}
+	return md
+}
+
+// incomingContext returns "ctx" with the headers of "r" selected by IncomingHeaderMatcher as incoming
+// metadata, and the client of "r" as peer.
+func incomingContext(ctx context.Context, r *http.Request) context.Context {
+	md := make(metadata.MD)
+	for key, values := range r.Header {
+		if k, ok := IncomingHeaderMatcher(key); ok {
+			md.Append(k, values...)
+		}
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: ClientAddr(r)})
+}
+
+// ClientAddr returns the address of the client of "r": the first address of the X-Forwarded-For header
+// if there is one, or the remote address of the connection otherwise.
+// X-Forwarded-For is only trustworthy if the gateway is served behind a proxy setting it.
+func ClientAddr(r *http.Request) net.Addr {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		client := strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		if client != "" {
+			return clientAddr(client)
+		}
+	}
+	return clientAddr(r.RemoteAddr)
+}
+
+// clientAddr is the address of the client of an HTTP request.
+type clientAddr string
+
+func (a clientAddr) Network() string {
+	return "tcp"
+}
+
+func (a clientAddr) String() string {
+	return string(a)
+}`))
+
+	contextTemplate = template.Must(template.New("context").Parse(`
+type requestHeaderKey struct{}
+
+type requestKey struct{}
+
+// requestState is the state of the HTTP request being served shared by its decoder and the service.
+type requestState struct {
+	r *http.Request
+	{{- if .Multipart}}
+	// multipart is the rest of the body left by StreamMultipart.
+	multipart *MultipartStream
+	{{- end}}
+}
+
+type serverMetadataKey struct{}
+
+// ServerMetadata holds the headers, trailers and status code written to the HTTP response of a request.
+type ServerMetadata struct {
+	Header  http.Header
+	Trailer http.Header
+	// Status is the status code of a successful response. It is 200 OK if it is 0.
+	Status int
+	// Body is the body set with SetResponseBody, if any.
+	Body io.Reader
+}
+
+// PopulateContext is a go-kit ServerBefore function storing the headers of "r"
+// and an empty ServerMetadata in the context. The headers selected by IncomingHeaderMatcher
+// are also stored as incoming metadata, and the client of "r" as peer.
+func PopulateContext(ctx context.Context, r *http.Request) context.Context {
+	ctx = incomingContext(ctx, r)
+	ctx = context.WithValue(ctx, requestKey{}, &requestState{r: r})
+	ctx = context.WithValue(ctx, requestHeaderKey{}, r.Header)
+	return context.WithValue(ctx, serverMetadataKey{}, &ServerMetadata{
+		Header:  make(http.Header),
+		Trailer: make(http.Header),
+	})
+}
+
+// RequestHeader returns the headers of the HTTP request being served.
+func RequestHeader(ctx context.Context) http.Header {
+	h, _ := ctx.Value(requestHeaderKey{}).(http.Header)
+	return h
+}
+
+// ServerMetadataFromContext returns the ServerMetadata of the HTTP request being served.
+func ServerMetadataFromContext(ctx context.Context) (*ServerMetadata, bool) {
+	md, ok := ctx.Value(serverMetadataKey{}).(*ServerMetadata)
+	return md, ok
+}
+
+// SetHeader adds "header" to the headers of the HTTP response of the request being served,
+// like grpc.SetHeader does for gRPC methods. It fails if the context is not of a request served by the gateway.
+func SetHeader(ctx context.Context, header http.Header) error {
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		return errNoServerMetadata
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			md.Header.Add(k, v)
+		}
+	}
+	return nil
+}
+
+// SetTrailer adds "trailer" to the trailers of the HTTP response of the request being served,
+// like grpc.SetTrailer does for gRPC methods. It fails if the context is not of a request served by the gateway.
+func SetTrailer(ctx context.Context, trailer http.Header) error {
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		return errNoServerMetadata
+	}
+	for k, vs := range trailer {
+		for _, v := range vs {
+			md.Trailer.Add(k, v)
+		}
+	}
+	return nil
+}
+
+// SetStatus sets the status code of the HTTP response of the request being served, if it succeeds.
+// Errors are written with the status code of the error encoder. It fails if the context is not of
+// a request served by the gateway.
+func SetStatus(ctx context.Context, code int) error {
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		return errNoServerMetadata
+	}
+	md.Status = code
+	return nil
+}
+
+// SetResponseBody sets the body of the HTTP response of the request being served, if it succeeds with a
+// google.api.HttpBody. EncodeMessage streams it in place of the data of the response, and closes it if it is
+// an io.Closer. It fails if the context is not of a request served by the gateway.
+func SetResponseBody(ctx context.Context, body io.Reader) error {
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		return errNoServerMetadata
+	}
+	md.Body = body
+	return nil
+}
+
+var errNoServerMetadata = errors.New("no ServerMetadata in the context")
+
+// WriteServerMetadata is a go-kit ServerAfter function writing the ServerMetadata in the context to "w".
+func WriteServerMetadata(ctx context.Context, w http.ResponseWriter) context.Context {
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	for k, vs := range md.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	for k, vs := range md.Trailer {
+		for _, v := range vs {
+			w.Header().Add(http.TrailerPrefix+k, v)
+		}
+	}
+	return ctx
+}
+
+// EncodeResponse wraps the response encoder "encode", so that the response is written with the
+// status code set with SetStatus instead of 200 OK.
+func EncodeResponse(encode func(context.Context, http.ResponseWriter, interface{}) error) func(context.Context, http.ResponseWriter, interface{}) error {
+	return EncodeResponseWithStatus(encode, ResponseStatus{})
+}
+
+// ResponseStatus is the status code of the successful responses of a method.
+type ResponseStatus struct {
+	// Code is the status code. It is 200 OK if it is 0. Responses with 204 No Content have no body.
+	Code int
+	// Location returns the name of the resource created by "response", if any. The Location header
+	// of the response is set to its URL, unless the service sets it.
+	Location func(response interface{}) string
+}
+
+// EncodeResponseWithStatus is EncodeResponse writing the response with the status code of "status",
+// unless the service sets another one with SetStatus.
+func EncodeResponseWithStatus(encode func(context.Context, http.ResponseWriter, interface{}) error, status ResponseStatus) func(context.Context, http.ResponseWriter, interface{}) error {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		code := status.Code
+		if md, ok := ServerMetadataFromContext(ctx); ok && md.Status != 0 {
+			code = md.Status
+		}
+		if status.Location != nil && w.Header().Get("Location") == "" {
+			if location := resourceLocation(ctx, status.Location(response)); location != "" {
+				w.Header().Set("Location", location)
+			}
+		}
+		switch code {
+		case 0, http.StatusOK:
+			return encode(ctx, w, response)
+		case http.StatusNoContent:
+			w.WriteHeader(code)
+			return nil
+		}
+		sw := &statusWriter{ResponseWriter: w, status: code}
+		if err := encode(ctx, sw, response); err != nil {
+			return err
+		}
+		if !sw.wroteHeader {
+			sw.WriteHeader(http.StatusOK)
+		}
+		return nil
+	}
+}
+
+// resourceLocation returns the URL of the resource "name" created by the request being served, i.e. its
+// path followed by the ID of the resource, as in AIP-133 where the path of the request ends with the
+// parent and the collection of the resource. It returns an empty string if it does not.
+func resourceLocation(ctx context.Context, name string) string {
+	state, ok := ctx.Value(requestKey{}).(*requestState)
+	i := strings.LastIndex(name, "/")
+	if !ok || i <= 0 {
+		return ""
+	}
+	path := strings.TrimSuffix(state.r.URL.Path, "/")
+	if path != name[:i] && !strings.HasSuffix(path, "/"+name[:i]) {
+		return ""
+	}
+	return path + name[i:]
+}
+
+// statusWriter replaces the 200 OK status code written to a http.ResponseWriter.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusOK {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}`))
+
+	marshalTemplate = template.Must(template.New("marshal").Parse(`
+// Media types of the marshalers registered by default.
+const (
+	MIMEJSON     = "application/json"
+	MIMEProtobuf = "application/x-protobuf"
+	MIMEForm     = "application/x-www-form-urlencoded"
+)
+
+// Marshaler encodes and decodes messages in the media type it is registered for.
+type Marshaler interface {
+	Marshal(v proto.Message) ([]byte, error)
+	Unmarshal(data []byte, v proto.Message) error
+}
+
+var marshalers = map[string]Marshaler{
+	MIMEJSON: &JSONMarshaler{
+		MarshalOptions: protojson.MarshalOptions{
+			EmitUnpopulated: {{.EmitUnpopulated}},
+			UseProtoNames:   {{.UseProtoNames}},
+			UseEnumNumbers:  {{.UseEnumNumbers}},
+		},
+		UnmarshalOptions: protojson.UnmarshalOptions{
+			DiscardUnknown: {{.DiscardUnknown}},
+		},
+	},
+	MIMEProtobuf: ProtoMarshaler{},
+	MIMEForm: &FormMarshaler{
+		UseProtoNames:  {{.UseProtoNames}},
+		DiscardUnknown: {{.DiscardUnknown}},
+	},
+}
+
+// RegisterMarshaler registers "m" for the media type "mimeType", replacing the marshaler registered for it.
+// It is not safe to call while requests are served.
+func RegisterMarshaler(mimeType string, m Marshaler) {
+	marshalers[strings.ToLower(mimeType)] = m
+}
+
+// MediaTypeError is the error of a request whose body is in, or which only accepts, media types
+// no marshaler is registered for.
+type MediaTypeError struct {
+	// MediaType is the Content-Type or the Accept header of the request.
+	MediaType string
+	// Accept reports whether the request is rejected for its Accept header.
+	Accept bool
+}
+
+func (e *MediaTypeError) Error() string {
+	if e.Accept {
+		return fmt.Sprintf("none of the accepted media types %q is supported", e.MediaType)
+	}
+	return fmt.Sprintf("unsupported media type %q", e.MediaType)
+}
+
+// StatusCode makes the request be rejected with 415 Unsupported Media Type, or with 406 Not Acceptable
+// for its Accept header.
+func (e *MediaTypeError) StatusCode() int {
+	if e.Accept {
+		return http.StatusNotAcceptable
+	}
+	return http.StatusUnsupportedMediaType
+}
+
+// BodyError is the error of a request whose body cannot be decoded.
+type BodyError struct {
+	Err error
+}
+
+func (e *BodyError) Error() string {
+	return "invalid request body: " + e.Err.Error()
+}
+
+// StatusCode makes the request be rejected with 400 Bad Request.
+func (e *BodyError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *BodyError) Unwrap() error {
+	return e.Err
+}
+
+// ParameterError is the error of a request whose parameter in the path, the query string,
+// a header or a cookie is missing or cannot be parsed. Only one of the names is set.
+type ParameterError struct {
+	// Path is the name of the path variable of the parameter.
+	Path string
+	// Query is the key of the parameter in the query string.
+	Query string
+	// Header is the name of the header of the parameter.
+	Header string
+	// Cookie is the name of the cookie of the parameter.
+	Cookie string
+	// Err is the error parsing the parameter. It is nil if the parameter is missing.
+	Err error
+}
+
+func (e *ParameterError) Error() string {
+	var name string
+	switch {
+	case e.Path != "":
+		name = "path parameter " + e.Path
+	case e.Query != "":
+		name = "query parameter " + e.Query
+	case e.Cookie != "":
+		name = "cookie " + e.Cookie
+	default:
+		name = "header " + e.Header
+	}
+	if e.Err == nil {
+		return "missing " + name
+	}
+	return "invalid " + name + ": " + e.Err.Error()
+}
+
+// StatusCode makes the request be rejected with 400 Bad Request.
+func (e *ParameterError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
+
+// FieldViolationError is the error of a request violating the rules of its fields, e.g. their
+// google.api.field_behavior or their validation rules.
+type FieldViolationError struct {
+	// BadRequest lists the violations.
+	BadRequest *errdetails.BadRequest
+}
+
+func (e *FieldViolationError) Error() string {
+	violations := make([]string, len(e.BadRequest.GetFieldViolations()))
+	for i, v := range e.BadRequest.GetFieldViolations() {
+		violations[i] = v.GetDescription()
+		if v.GetField() != "" {
+			violations[i] = v.GetField() + ": " + violations[i]
+		}
+	}
+	return "invalid request: " + strings.Join(violations, "; ")
+}
+
+// StatusCode makes the request be rejected with 400 Bad Request.
+func (e *FieldViolationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// GRPCStatus returns the InvalidArgument status of the error, with the violations as its details.
+func (e *FieldViolationError) GRPCStatus() *status.Status {
+	st, err := status.New(codes.InvalidArgument, e.Error()).WithDetails(e.BadRequest)
+	if err != nil {
+		return status.New(codes.InvalidArgument, e.Error())
+	}
+	return st
+}
+
+// MarshalJSON returns the google.rpc.Status of GRPCStatus as JSON, so that the error encoders write the
+// violations in its google.rpc.BadRequest detail as an application/json body.
+func (e *FieldViolationError) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(e.GRPCStatus().Proto())
+}
+
+// DecodePath sets the fields of "v" at the field paths "names", e.g. "shelf.name", to the values of the
+// path variables they are bound to in the request "r" routed by Router. The values are parsed as in
+// DecodeQuery.
+func DecodePath(r *http.Request, v proto.Message, names ...string) error {
+	fields := &FormMarshaler{}
+	for _, name := range names {
+		if err := fields.populate(v.ProtoReflect(), name, []string{PathParam(r, name)}); err != nil {
+			return &ParameterError{Path: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// DecodeQuery sets the fields of "v" to the query parameters of "r" named after their field paths, as
+// forms are decoded, ignoring unknown ones. Well-known types are parsed from their JSON representation:
+// RFC 3339 timestamps, durations such as "1.5s", comma-separated field mask paths, the values of wrappers,
+// and JSON structs and values.
+// The parameters within the field paths of "filter", the filter_<Handler> of the binding decoded, are
+// ignored too, so that they cannot override the fields bound to the path or the body.
+func DecodeQuery(r *http.Request, v proto.Message, filter *utilities.DoubleArray) error {
+	fields := &FormMarshaler{DiscardUnknown: true}
+	for key, values := range r.URL.Query() {
+		if filter != nil && filter.HasCommonPrefix(queryFieldPath(v.ProtoReflect().Descriptor(), key)) {
+			continue
+		}
+		if err := fields.populate(v.ProtoReflect(), key, values); err != nil {
+			return &ParameterError{Query: key, Err: err}
+		}
+	}
+	return nil
+}
+
+// queryFieldPath returns the proto names of the fields of "md" named by the query parameter "key",
+// with proto or JSON names. Unknown names are kept as they are.
+func queryFieldPath(md protoreflect.MessageDescriptor, key string) []string {
+	names := strings.Split(key, ".")
+	for i, name := range names {
+		if md == nil {
+			break
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(name)
+		}
+		if fd == nil {
+			break
+		}
+		names[i] = string(fd.Name())
+		md = fd.Message()
+	}
+	return names
+}
+
+// recordedBody is a request body recording what is read from it.
+type recordedBody struct {
+	io.ReadCloser
+	record *bytes.Buffer
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.record.Write(p[:n])
+	return n, err
+}
+
+// RecordBody makes the body of "r" be recorded as it is read, returning the record.
+func RecordBody(r *http.Request) *bytes.Buffer {
+	record := new(bytes.Buffer)
+	r.Body = &recordedBody{ReadCloser: r.Body, record: record}
+	return record
+}
+
+// UpdateMask returns the field mask of the fields of "m" present in the JSON body "body" of "r",
+// nested messages being masked by the fields present in them, as in AIP-134. The fields at the paths
+// "omit", e.g. OUTPUT_ONLY ones, and the fields within them are left out of the mask.
+// It returns nil if the body is not a JSON object, e.g. if it is empty or in another media type.
+func UpdateMask(r *http.Request, body []byte, m proto.Message, omit ...string) (*fieldmaskpb.FieldMask, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != MIMEJSON {
+			return nil, nil
+		}
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, nil
+	}
+	mask := &fieldmaskpb.FieldMask{}
+	appendMaskPaths(mask, m.ProtoReflect().Descriptor(), "", fields)
+	paths := mask.Paths[:0]
+	for _, path := range mask.Paths {
+		if !omitted(path, omit) {
+			paths = append(paths, path)
+		}
+	}
+	mask.Paths = paths
+	return mask, nil
+}
+
+// omitted reports whether "path" is one of "omit", or within one of them.
+func omitted(path string, omit []string) bool {
+	for _, o := range omit {
+		if path == o || strings.HasPrefix(path, o+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// appendMaskPaths appends the paths of the fields of "md" in "fields", prefixed with "prefix", to "mask".
+func appendMaskPaths(mask *fieldmaskpb.FieldMask, md protoreflect.MessageDescriptor, prefix string, fields map[string]json.RawMessage) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fd := md.Fields().ByName(protoreflect.Name(key))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(key)
+		}
+		if fd == nil {
+			continue
+		}
+		path := prefix + string(fd.Name())
+		var nested map[string]json.RawMessage
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && !isWellKnown(fd.Message()) &&
+			json.Unmarshal(fields[key], &nested) == nil && len(nested) != 0 {
+			appendMaskPaths(mask, fd.Message(), path+".", nested)
+			continue
+		}
+		mask.Paths = append(mask.Paths, path)
+	}
+}
+
+// RequestMarshaler returns the marshaler of the Content-Type of "r", JSON if it has none.
+func RequestMarshaler(r *http.Request) (Marshaler, error) {
+	return marshalerOf(r.Header.Get("Content-Type"))
+}
+
+// marshalerOf returns the marshaler of the media type of "contentType", JSON if it is empty.
+func marshalerOf(contentType string) (Marshaler, error) {
+	if contentType == "" {
+		return marshalers[MIMEJSON], nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, &MediaTypeError{MediaType: contentType}
+	}
+	m, ok := marshalers[mediaType]
+	if !ok {
+		return nil, &MediaTypeError{MediaType: contentType}
+	}
+	return m, nil
+}
+
+// ResponseMarshaler returns the media type and the marshaler the response of the request being served
+// is encoded with: the registered one its Accept header prefers, JSON if it has none.
+func ResponseMarshaler(ctx context.Context) (string, Marshaler, error) {
+	accept := strings.Join(RequestHeader(ctx).Values("Accept"), ",")
+	if accept == "" {
+		return MIMEJSON, marshalers[MIMEJSON], nil
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	var registered []string
+	for mediaType := range marshalers {
+		registered = append(registered, mediaType)
+	}
+	sort.Strings(registered)
+	for _, r := range ranges {
+		if m, ok := marshalers[r.mediaType]; ok {
+			return r.mediaType, m, nil
+		}
+		if !strings.HasSuffix(r.mediaType, "/*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(r.mediaType, "*")
+		if strings.HasPrefix(MIMEJSON, prefix) || r.mediaType == "*/*" {
+			return MIMEJSON, marshalers[MIMEJSON], nil
+		}
+		for _, mediaType := range registered {
+			if strings.HasPrefix(mediaType, prefix) {
+				return mediaType, marshalers[mediaType], nil
+			}
+		}
+	}
+	return "", nil, &MediaTypeError{MediaType: accept, Accept: true}
+}
+
+// NegotiateResponse wraps the request decoder "decode", so that the requests whose Accept header prefers no
+// registered media type are rejected with a *MediaTypeError, 406 Not Acceptable, before they are decoded
+// and the service is called.
+func NegotiateResponse(decode func(context.Context, *http.Request) (interface{}, error)) func(context.Context, *http.Request) (interface{}, error) {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if _, _, err := ResponseMarshaler(ctx); err != nil {
+			return nil, err
+		}
+		return decode(ctx, r)
+	}
+}
+
+// HttpBodyBufferLimit is the size above which the bodies of requests decoded into a google.api.HttpBody
+// are not read into its data, but left to be streamed with RequestBody.
+var HttpBodyBufferLimit int64 = 4 << 20
+
+// DecodeMessage decodes the body of "r" into "v" with the marshaler of its Content-Type.
+// "v" is left as it is if the body is empty.
+{{- if .Multipart}} Bodies in multipart/form-data are decoded as forms,
+// their files into bytes fields, within the limits of Multipart.
+{{- end}}
+// The raw body and Content-Type are decoded into a google.api.HttpBody, unless the body is larger than
+// HttpBodyBufferLimit.
+func DecodeMessage(r *http.Request, v proto.Message) error {
+	if body, ok := v.(*httpbody.HttpBody); ok {
+		return decodeHttpBody(r, body)
+	}
+	{{- if .Multipart}}
+	if isMultipart(r) {
+		return decodeMultipart(r, v)
+	}
+	{{- end}}
+	m, err := RequestMarshaler(r)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return &BodyError{Err: err}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := m.Unmarshal(data, v); err != nil {
+		return &BodyError{Err: err}
+	}
+	return nil
+}
+
+func decodeHttpBody(r *http.Request, body *httpbody.HttpBody) error {
+	body.ContentType = r.Header.Get("Content-Type")
+	if r.ContentLength > HttpBodyBufferLimit {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, HttpBodyBufferLimit+1))
+	if err != nil {
+		return &BodyError{Err: err}
+	}
+	if int64(len(data)) > HttpBodyBufferLimit {
+		// the body is left to be streamed, starting with what was read of it.
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+		return nil
+	}
+	body.Data = data
+	return nil
+}
+
+// RequestBody returns the body of the HTTP request being served. The bodies of requests decoded into a
+// google.api.HttpBody without data, for being larger than HttpBodyBufferLimit, are read from it.
+// Other bodies are read by Decode already.
+func RequestBody(ctx context.Context) io.Reader {
+	if state, ok := ctx.Value(requestKey{}).(*requestState); ok {
+		return state.r.Body
+	}
+	return http.NoBody
+}
+
+// formFields returns the FormMarshaler form fields are decoded with, the one registered for forms if any.
+func formFields() *FormMarshaler {
+	if m, ok := marshalers[MIMEForm].(*FormMarshaler); ok {
+		return m
+	}
+	return &FormMarshaler{}
+}
+{{- if .Multipart}}
+
+// MultipartOptions are the limits of the multipart/form-data requests DecodeMessage and StreamMultipart decode.
+type MultipartOptions struct {
+	// MaxSize is the size of the largest body accepted. Larger ones are rejected with 413 Request Entity Too Large.
+	MaxSize int64
+	// MaxMemory is the size of the file parts DecodeMessage keeps in memory while parsing the body.
+	// Larger ones are stored in temporary files.
+	MaxMemory int64
+}
+
+// Multipart are the limits of multipart/form-data requests. Requests in multipart/form-data are rejected
+// with 415 Unsupported Media Type if it is nil.
+var Multipart = &MultipartOptions{
+	MaxSize:   32 << 20,
+	MaxMemory: 8 << 20,
+}
+
+// RequestTooLargeError is the error of a request whose body is larger than the limit.
+type RequestTooLargeError struct {
+	Limit int64
+}
+
+func (e *RequestTooLargeError) Error() string {
+	return fmt.Sprintf("request body larger than %d bytes", e.Limit)
+}
+
+// StatusCode makes the request be rejected with 413 Request Entity Too Large.
+func (e *RequestTooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// limitedBody fails reads beyond "remaining" bytes of a request body with a *RequestTooLargeError,
+// recording that the limit was exceeded.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			b.exceeded = true
+			return 0, &RequestTooLargeError{Limit: Multipart.MaxSize}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// multipartBody limits the body of the multipart/form-data request "r" to Multipart.MaxSize.
+func multipartBody(r *http.Request) (*limitedBody, error) {
+	if Multipart == nil {
+		return nil, &MediaTypeError{MediaType: r.Header.Get("Content-Type")}
+	}
+	if r.ContentLength > Multipart.MaxSize {
+		return nil, &RequestTooLargeError{Limit: Multipart.MaxSize}
+	}
+	body := &limitedBody{ReadCloser: r.Body, remaining: Multipart.MaxSize}
+	r.Body = body
+	return body, nil
+}
+
+// multipartError returns the error of decoding a multipart/form-data body.
+func multipartError(body *limitedBody, err error) error {
+	if body.exceeded {
+		return &RequestTooLargeError{Limit: Multipart.MaxSize}
+	}
+	return &BodyError{Err: err}
+}
+
+// isMultipart reports whether the body of "r" is multipart/form-data.
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// decodeMultipart decodes the multipart/form-data body of "r" into "v": the form fields into the fields
+// they are named after, as in forms, and the content of the files into the bytes fields named after
+// their form fields, appended to repeated ones.
+func decodeMultipart(r *http.Request, v proto.Message) error {
+	body, err := multipartBody(r)
+	if err != nil {
+		return err
+	}
+	if err := r.ParseMultipartForm(Multipart.MaxMemory); err != nil {
+		return multipartError(body, err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	fields := formFields()
+	for key, values := range r.MultipartForm.Value {
+		if err := fields.populate(v.ProtoReflect(), key, values); err != nil {
+			return &BodyError{Err: err}
+		}
+	}
+	for key, files := range r.MultipartForm.File {
+		for _, fh := range files {
+			f, err := fh.Open()
+			if err != nil {
+				return &BodyError{Err: err}
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return &BodyError{Err: err}
+			}
+			if err := fields.setFile(v.ProtoReflect(), key, data); err != nil {
+				return &BodyError{Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// setFile sets the bytes field named "key" within "msg" to "data", or appends "data" to it if it is repeated.
+func (m *FormMarshaler) setFile(msg protoreflect.Message, key string, data []byte) error {
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(key))
+	if fd == nil {
+		fd = msg.Descriptor().Fields().ByJSONName(key)
+	}
+	if fd == nil {
+		if m.DiscardUnknown {
+			return nil
+		}
+		return fmt.Errorf("unknown field %q in %s", key, msg.Descriptor().FullName())
+	}
+	if fd.Kind() != protoreflect.BytesKind || fd.IsMap() {
+		return fmt.Errorf("file %q cannot be decoded into field %s, which is not bytes", key, fd.FullName())
+	}
+	if fd.IsList() {
+		msg.Mutable(fd).List().Append(protoreflect.ValueOfBytes(data))
+		return nil
+	}
+	msg.Set(fd, protoreflect.ValueOfBytes(data))
+	return nil
+}
+
+// MultipartStream is the rest of a multipart/form-data body StreamMultipart left to the service,
+// starting with its first file.
+type MultipartStream struct {
+	reader *multipart.Reader
+	next   *multipart.Part
+	body   *limitedBody
+}
+
+// NextPart returns the next part of the body, or io.EOF after the last one.
+// Bodies larger than Multipart.MaxSize fail with a *RequestTooLargeError, as do the reads of their parts.
+func (s *MultipartStream) NextPart() (*multipart.Part, error) {
+	if p := s.next; p != nil {
+		s.next = nil
+		return p, nil
+	}
+	p, err := s.reader.NextPart()
+	if err != nil && s.body.exceeded {
+		return nil, &RequestTooLargeError{Limit: Multipart.MaxSize}
+	}
+	return p, err
+}
+
+// StreamMultipart decodes the form fields of the multipart/form-data body of "r" preceding its first file
+// into "v", as DecodeMessage does, and leaves the rest of the body, from the first file, to be streamed by
+// the service with MultipartStreamFromContext. "ctx" is the context of the request "r", as passed to Decode.
+func StreamMultipart(ctx context.Context, r *http.Request, v proto.Message) error {
+	if !isMultipart(r) {
+		return &MediaTypeError{MediaType: r.Header.Get("Content-Type")}
+	}
+	body, err := multipartBody(r)
+	if err != nil {
+		return err
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return multipartError(body, err)
+	}
+	fields := formFields()
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return multipartError(body, err)
+		}
+		if part.FileName() != "" {
+			if state, ok := ctx.Value(requestKey{}).(*requestState); ok {
+				state.multipart = &MultipartStream{reader: reader, next: part, body: body}
+			}
+			return nil
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return multipartError(body, err)
+		}
+		if err := fields.populate(v.ProtoReflect(), part.FormName(), []string{string(value)}); err != nil {
+			return &BodyError{Err: err}
+		}
+	}
+}
+
+// MultipartStreamFromContext returns the files of the multipart/form-data body of the request being served
+// left by StreamMultipart, and whether there are any.
+func MultipartStreamFromContext(ctx context.Context) (*MultipartStream, bool) {
+	state, ok := ctx.Value(requestKey{}).(*requestState)
+	if !ok || state.multipart == nil {
+		return nil, false
+	}
+	return state.multipart, true
+}
+{{- end}}
+
+// EncodeMessage writes the message "v" to "w" with the marshaler of the media type the request being served
+// accepts. It can be used as the Encode method of the handlers.
+// The data of a google.api.HttpBody is written as it is, with its content type, or the body set with
+// SetResponseBody is streamed in its place.
+func EncodeMessage(ctx context.Context, w http.ResponseWriter, v interface{}) error {
+	if body, ok := v.(*httpbody.HttpBody); ok {
+		return encodeHttpBody(ctx, w, body)
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot encode %T, which is not a proto.Message", v)
+	}
+	mediaType, m, err := ResponseMarshaler(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := m.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", mediaType)
+	_, err = w.Write(data)
+	return err
+}
+
+func encodeHttpBody(ctx context.Context, w http.ResponseWriter, body *httpbody.HttpBody) error {
+	contentType := body.GetContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok || md.Body == nil {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body.GetData())))
+		_, err := w.Write(body.GetData())
+		return err
+	}
+	if c, ok := md.Body.(io.Closer); ok {
+		defer c.Close()
+	}
+	_, err := io.Copy(w, md.Body)
+	return err
+}
+
+// JSONMarshaler marshals messages with protojson.
+type JSONMarshaler struct {
+	MarshalOptions   protojson.MarshalOptions
+	UnmarshalOptions protojson.UnmarshalOptions
+}
+
+func (m *JSONMarshaler) Marshal(v proto.Message) ([]byte, error) {
+	return m.MarshalOptions.Marshal(v)
+}
+
+func (m *JSONMarshaler) Unmarshal(data []byte, v proto.Message) error {
+	return m.UnmarshalOptions.Unmarshal(data, v)
+}
+
+// ProtoMarshaler marshals messages in the protobuf wire format.
+type ProtoMarshaler struct{}
+
+func (ProtoMarshaler) Marshal(v proto.Message) ([]byte, error) {
+	return proto.Marshal(v)
+}
+
+func (ProtoMarshaler) Unmarshal(data []byte, v proto.Message) error {
+	return proto.Unmarshal(data, v)
+}
+
+// FormMarshaler marshals messages as URL encoded forms. The keys are the names of the fields,
+// proto or JSON ones, joined with dots for nested messages, and repeated fields have a value per element.
+// Well-known types are values, as in DecodeQuery. Maps and other repeated messages are not supported.
+type FormMarshaler struct {
+	// UseProtoNames names the fields of marshaled forms after their proto names instead of their JSON names.
+	UseProtoNames bool
+	// DiscardUnknown ignores unknown keys instead of failing.
+	DiscardUnknown bool
+}
+
+func (m *FormMarshaler) Marshal(v proto.Message) ([]byte, error) {
+	values := make(url.Values)
+	if err := m.formValues(v.ProtoReflect(), "", values); err != nil {
+		return nil, err
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (m *FormMarshaler) formValues(msg protoreflect.Message, prefix string, values url.Values) error {
+	var err error
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		key := prefix + fd.JSONName()
+		if m.UseProtoNames {
+			key = prefix + string(fd.Name())
+		}
+		switch {
+		case fd.IsMap() || (fd.IsList() && fd.Message() != nil && !isWellKnown(fd.Message())):
+			err = fmt.Errorf("field %s cannot be encoded in a form", fd.FullName())
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				var s string
+				if s, err = formString(fd, list.Get(i)); err != nil {
+					return false
+				}
+				values.Add(key, s)
+			}
+		case fd.Message() != nil && !isWellKnown(fd.Message()):
+			err = m.formValues(v.Message(), key+".", values)
+		default:
+			var s string
+			if s, err = formString(fd, v); err == nil {
+				values.Add(key, s)
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+func formString(fd protoreflect.FieldDescriptor, v protoreflect.Value) (string, error) {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name()), nil
+		}
+		return strconv.Itoa(int(v.Enum())), nil
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+	case protoreflect.MessageKind:
+		return wellKnownString(v.Message())
+	}
+	return v.String(), nil
+}
+
+func (m *FormMarshaler) Unmarshal(data []byte, v proto.Message) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	for key, vs := range values {
+		if err := m.populate(v.ProtoReflect(), key, vs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// populate sets the field at "key" within "msg" to "values".
+func (m *FormMarshaler) populate(msg protoreflect.Message, key string, values []string) error {
+	names := strings.Split(key, ".")
+	for i, name := range names {
+		fields := msg.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			if m.DiscardUnknown {
+				return nil
+			}
+			return fmt.Errorf("unknown field %q in %s", key, msg.Descriptor().FullName())
+		}
+		wellKnown := fd.Message() != nil && isWellKnown(fd.Message())
+		if fd.IsMap() || (fd.IsList() && fd.Message() != nil && !wellKnown) {
+			return fmt.Errorf("field %s cannot be decoded from a form", fd.FullName())
+		}
+		if i < len(names)-1 {
+			if fd.Message() == nil || wellKnown {
+				return fmt.Errorf("field %s of %q is not a message", fd.FullName(), key)
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+		if fd.Message() != nil && !wellKnown {
+			return fmt.Errorf("field %s cannot be decoded from a form", fd.FullName())
+		}
+		if fd.IsList() {
+			list := msg.Mutable(fd).List()
+			for _, s := range values {
+				v := list.NewElement()
+				if err := setFormValue(fd, &v, s); err != nil {
+					return err
+				}
+				list.Append(v)
+			}
+			return nil
+		}
+		v := msg.NewField(fd)
+		if err := setFormValue(fd, &v, values[len(values)-1]); err != nil {
+			return err
+		}
+		msg.Set(fd, v)
+	}
+	return nil
+}
+
+// setFormValue parses the form value "s" of the field "fd" into "v", an empty value of the field.
+func setFormValue(fd protoreflect.FieldDescriptor, v *protoreflect.Value, s string) error {
+	if fd.Message() == nil {
+		parsed, err := formValue(fd, s)
+		*v = parsed
+		return err
+	}
+	if err := parseWellKnown(v.Message(), s); err != nil {
+		return fmt.Errorf("invalid value %q of field %s: %v", s, fd.FullName(), err)
+	}
+	return nil
+}
+
+// wellKnownTypes are the well-known types parsed from strings in forms, paths and query strings.
+var wellKnownTypes = map[protoreflect.FullName]bool{
+	"google.protobuf.Timestamp":   true,
+	"google.protobuf.Duration":    true,
+	"google.protobuf.FieldMask":   true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.BytesValue":  true,
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.UInt64Value": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.Struct":      true,
+	"google.protobuf.ListValue":   true,
+	"google.protobuf.Value":       true,
+}
+
+func isWellKnown(md protoreflect.MessageDescriptor) bool {
+	return wellKnownTypes[md.FullName()]
+}
+
+// parseWellKnown parses "s" into the message "m" of a well-known type.
+// Values which are not JSON are parsed into google.protobuf.Value as strings.
+func parseWellKnown(m protoreflect.Message, s string) error {
+	md := m.Descriptor()
+	switch md.FullName() {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration":
+		quoted, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		return protojson.Unmarshal(quoted, m.Interface())
+	case "google.protobuf.FieldMask":
+		paths := m.Mutable(md.Fields().ByName("paths")).List()
+		for _, path := range strings.Split(s, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths.Append(protoreflect.ValueOfString(path))
+			}
+		}
+		return nil
+	case "google.protobuf.Struct", "google.protobuf.ListValue":
+		return protojson.Unmarshal([]byte(s), m.Interface())
+	case "google.protobuf.Value":
+		if json.Valid([]byte(s)) {
+			return protojson.Unmarshal([]byte(s), m.Interface())
+		}
+		quoted, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		return protojson.Unmarshal(quoted, m.Interface())
+	}
+	// Wrappers hold their value in their "value" field.
+	fd := md.Fields().ByName("value")
+	v, err := formValue(fd, s)
+	if err != nil {
+		return err
+	}
+	m.Set(fd, v)
+	return nil
+}
+
+// wellKnownString returns the string "m" of a well-known type is parsed from by parseWellKnown.
+func wellKnownString(m protoreflect.Message) (string, error) {
+	md := m.Descriptor()
+	switch md.FullName() {
+	case "google.protobuf.FieldMask":
+		paths := m.Get(md.Fields().ByName("paths")).List()
+		s := make([]string, paths.Len())
+		for i := range s {
+			s[i] = paths.Get(i).String()
+		}
+		return strings.Join(s, ","), nil
+	case "google.protobuf.Struct", "google.protobuf.ListValue", "google.protobuf.Value":
+		b, err := protojson.Marshal(m.Interface())
+		return string(b), err
+	case "google.protobuf.Timestamp", "google.protobuf.Duration":
+		b, err := protojson.Marshal(m.Interface())
+		if err != nil {
+			return "", err
+		}
+		var s string
+		err = json.Unmarshal(b, &s)
+		return s, err
+	}
+	fd := md.Fields().ByName("value")
+	return formString(fd, m.Get(fd))
+}
+
+// formValue parses the form value "s" of the scalar field "fd".
+func formValue(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	var v interface{}
+	var err error
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = s
+	case protoreflect.BytesKind:
+		v, err = base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			v, err = base64.URLEncoding.DecodeString(s)
+		}
+	case protoreflect.BoolKind:
+		v, err = strconv.ParseBool(s)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		v = protoreflect.EnumNumber(n)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		v = int32(n)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 32)
+		v = uint32(n)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err = strconv.ParseUint(s, 10, 64)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		v = float32(f)
+	case protoreflect.DoubleKind:
+		v, err = strconv.ParseFloat(s, 64)
+	default:
+		return protoreflect.Value{}, fmt.Errorf("field %s cannot be decoded from a form", fd.FullName())
+	}
+	if err != nil {
+		return protoreflect.Value{}, fmt.Errorf("invalid value %q of field %s: %v", s, fd.FullName(), err)
+	}
+	return protoreflect.ValueOf(v), nil
+}`))
+
+	behaviorTemplate = template.Must(template.New("behavior").Parse(`
+// ClearFields clears the fields of "m" at "paths", e.g. "book.create_time", as OUTPUT_ONLY fields are.
+func ClearFields(m proto.Message, paths ...string) {
+	for _, path := range paths {
+		if msg, fd := lookupField(m.ProtoReflect(), path); fd != nil {
+			msg.Clear(fd)
+		}
+	}
+}
+
+// RequireFields returns a *FieldViolationError if any field of "m" at "paths" is not set, as REQUIRED fields
+// must be. Fields within messages which are not set are not required, only the messages themselves if they are.
+func RequireFields(m proto.Message, paths ...string) error {
+	br := &errdetails.BadRequest{}
+	for _, path := range paths {
+		if msg, fd := lookupField(m.ProtoReflect(), path); fd != nil && !msg.Has(fd) {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       path,
+				Description: "required field is missing",
+			})
+		}
+	}
+	if len(br.FieldViolations) != 0 {
+		return &FieldViolationError{BadRequest: br}
+	}
+	return nil
+}
+
+// RejectMasked returns a *FieldViolationError if "mask" masks any of the fields at "paths", or the fields
+// within them, as IMMUTABLE fields cannot be updated.
+func RejectMasked(mask *fieldmaskpb.FieldMask, paths ...string) error {
+	br := &errdetails.BadRequest{}
+	for _, masked := range mask.GetPaths() {
+		for _, path := range paths {
+			if masked == path || strings.HasPrefix(masked, path+".") {
+				br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       masked,
+					Description: "immutable field cannot be updated",
+				})
+			}
+		}
+	}
+	if len(br.FieldViolations) != 0 {
+		return &FieldViolationError{BadRequest: br}
+	}
+	return nil
+}
+
+// lookupField returns the field at "path" within "msg" and the message holding it.
+// It returns a nil field if a message on the path is not set.
+func lookupField(msg protoreflect.Message, path string) (protoreflect.Message, protoreflect.FieldDescriptor) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, nil
+		}
+		if i == len(names)-1 {
+			return msg, fd
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() || !msg.Has(fd) {
+			return nil, nil
+		}
+		msg = msg.Get(fd).Message()
+	}
+	return nil, nil
+}`))
+
+	clientTemplate = template.Must(template.New("client").Parse(`
+// ClientTarget returns the URL of the gateway at "instance", e.g. "https://example.com", the clients send
+// their requests to. Instances without a scheme, e.g. "localhost:8080", are reached with plain HTTP.
+func ClientTarget(instance string) (*url.URL, error) {
+	if !strings.HasPrefix(instance, "http://") && !strings.HasPrefix(instance, "https://") {
+		instance = "http://" + instance
+	}
+	return url.Parse(instance)
+}
+
+// pathVariable matches the variables of path templates, e.g. "{name}" or "{name=shelves/*}".
+var pathVariable = regexp.MustCompile("\\{([^}=]+)(?:=([^}]*))?\\}")
+
+// EncodePath appends the path template "tmpl" of a binding to the path of the request "r" of a client,
+// its variables replaced by the fields of "v" at their field paths, formatted as in EncodeQuery.
+// The values of variables matching several segments, e.g. "{name=shelves/*}", keep their slashes.
+func EncodePath(r *http.Request, v proto.Message, tmpl string) error {
+	var err error
+	path := pathVariable.ReplaceAllStringFunc(tmpl, func(variable string) string {
+		match := pathVariable.FindStringSubmatch(variable)
+		value, verr := fieldString(v.ProtoReflect(), match[1])
+		if verr != nil || value == "" {
+			if err == nil {
+				err = &ParameterError{Path: match[1], Err: verr}
+			}
+			return ""
+		}
+		if !strings.Contains(match[2], "/") && !strings.Contains(match[2], "**") {
+			return url.PathEscape(value)
+		}
+		segments := strings.Split(value, "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		return strings.Join(segments, "/")
+	})
+	if err != nil {
+		return err
+	}
+	escaped := strings.TrimSuffix(r.URL.EscapedPath(), "/") + path
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return err
+	}
+	r.URL.Path, r.URL.RawPath = unescaped, escaped
+	return nil
+}
+
+// EncodeHeader sets the header "name" of the request "r" of a client to the field of "v" at the field path
+// "field", formatted as in EncodeQuery, if it is set.
+func EncodeHeader(r *http.Request, v proto.Message, name, field string) error {
+	value, err := fieldString(v.ProtoReflect(), field)
+	if err != nil {
+		return &ParameterError{Header: name, Err: err}
+	}
+	if value != "" {
+		r.Header.Set(name, value)
+	}
+	return nil
+}
+
+// EncodeCookie adds the cookie "name" to the request "r" of a client, with the field of "v" at the field path
+// "field" as its value, formatted as in EncodeQuery, if it is set.
+func EncodeCookie(r *http.Request, v proto.Message, name, field string) error {
+	value, err := fieldString(v.ProtoReflect(), field)
+	if err != nil {
+		return &ParameterError{Cookie: name, Err: err}
+	}
+	if value != "" {
+		r.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	return nil
+}
+
+// EncodeQuery sets the query string of the request "r" of a client to the fields of "v" but the ones at
+// the field paths "bound", e.g. the ones in its path, as DecodeQuery decodes them.
+func EncodeQuery(r *http.Request, v proto.Message, bound ...string) error {
+	msg := proto.Clone(v).ProtoReflect()
+	for _, path := range bound {
+		if parent, fd, err := messageField(msg, path); err == nil && parent.Has(fd) {
+			parent.Clear(fd)
+		}
+	}
+	values := make(url.Values)
+	if err := formFields().formValues(msg, "", values); err != nil {
+		return err
+	}
+	r.URL.RawQuery = values.Encode()
+	return nil
+}
+
+// EncodeBody encodes the field of "v" at the field path "field", or "v" itself if it is "*", as the JSON
+// body of the request "r" of a client. The data of a google.api.HttpBody is sent as it is, with its content type.
+func EncodeBody(r *http.Request, v proto.Message, field string) error {
+	msg := v.ProtoReflect()
+	if field != "*" {
+		parent, fd, err := messageField(msg, field)
+		if err != nil {
+			return err
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			// the JSON of other fields is the one of their value in a message holding only them.
+			only := parent.Type().New()
+			if parent.Has(fd) {
+				only.Set(fd, parent.Get(fd))
+			}
+			data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(only.Interface())
+			if err != nil {
+				return err
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				return err
+			}
+			setRequestBody(r, MIMEJSON, fields[string(fd.Name())])
+			return nil
+		}
+		msg = parent.Get(fd).Message()
+	}
+	if body, ok := msg.Interface().(*httpbody.HttpBody); ok {
+		setRequestBody(r, body.GetContentType(), body.GetData())
+		return nil
+	}
+	data, err := marshalers[MIMEJSON].Marshal(msg.Interface())
+	if err != nil {
+		return err
+	}
+	setRequestBody(r, MIMEJSON, data)
+	return nil
+}
+
+func setRequestBody(r *http.Request, contentType string, data []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	r.ContentLength = int64(len(data))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+}
+
+// ResponseError is the error of a response of the gateway to a client whose status is not 2xx, and whose
+// body is not a google.rpc.Status.
+type ResponseError struct {
+	// Code is the HTTP status code of the response.
+	Code int
+	// Body is the body of the response.
+	Body []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Code, http.StatusText(e.Code), bytes.TrimSpace(e.Body))
+}
+
+// StatusCode makes the error be encoded with the status code of the response.
+func (e *ResponseError) StatusCode() int {
+	return e.Code
+}
+
+// DecodeResponse decodes the body of the response "resp" of the gateway to a client into "v" with the
+// marshaler of its Content-Type. The google.rpc.Status of responses which are not 2xx is returned as a
+// gRPC status error, and their other bodies as a *ResponseError.
+func DecodeResponse(resp *http.Response, v proto.Message) error {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		st := &spb.Status{}
+		if m, err := marshalerOf(contentType); err == nil && m.Unmarshal(data, st) == nil && st.GetCode() != 0 {
+			return status.ErrorProto(st)
+		}
+		return &ResponseError{Code: resp.StatusCode, Body: data}
+	}
+	if body, ok := v.(*httpbody.HttpBody); ok {
+		body.ContentType = contentType
+		body.Data = data
+		return nil
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	m, err := marshalerOf(contentType)
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal(data, v)
+}
+
+// fieldString returns the field of "msg" at "path", e.g. "shelf.name", as it is encoded in forms, paths,
+// headers and cookies.
+// It returns an empty string if the field is not set.
+func fieldString(msg protoreflect.Message, path string) (string, error) {
+	parent, fd, err := messageField(msg, path)
+	if err != nil {
+		return "", err
+	}
+	if fd.IsList() || fd.IsMap() || (fd.Message() != nil && !isWellKnown(fd.Message())) {
+		return "", fmt.Errorf("field %s cannot be encoded in a string", fd.FullName())
+	}
+	if !parent.Has(fd) {
+		return "", nil
+	}
+	return formString(fd, parent.Get(fd))
+}
+
+// messageField returns the field at "path" within "msg", e.g. "shelf.name", and the message holding it.
+func messageField(msg protoreflect.Message, path string) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, nil, fmt.Errorf("unknown field %q in %s", path, msg.Descriptor().FullName())
+		}
+		if i == len(names)-1 {
+			return msg, fd, nil
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return nil, nil, fmt.Errorf("field %s of %q is not a message", fd.FullName(), path)
+		}
+		msg = msg.Get(fd).Message()
+	}
+	return nil, nil, fmt.Errorf("empty field path")
+}`))
+
+	validateTemplate = template.Must(template.New("validate").Parse(`
+// Validator validates the requests besides their Validate methods, e.g. with protovalidate:
+//
+//	v, err := protovalidate.New()
+//	...
+//	gen.Validator = func(m proto.Message) error { return v.Validate(m) }
+//
+// The violations of the errors of protovalidate are reported per field, and so are errors with Field() and
+// Description() methods. Other errors are reported as a violation of the request as a whole. Errors joined
+// with errors.Join are reported one by one.
+var Validator func(proto.Message) error
+
+// ValidateRequest validates "req" with its ValidateAll method, or its Validate method if it has none,
+// as generated by protoc-gen-validate, and with Validator if it is set. Violations are returned as a
+// *FieldViolationError.
+func ValidateRequest(req proto.Message) error {
+	var err error
+	switch v := req.(type) {
+	case interface{ ValidateAll() error }:
+		err = v.ValidateAll()
+	case interface{ Validate() error }:
+		err = v.Validate()
+	}
+	if err == nil && Validator != nil {
+		err = Validator(req)
+	}
+	if err == nil {
+		return nil
+	}
+	var fve *FieldViolationError
+	if errors.As(err, &fve) {
+		return fve
+	}
+	br := &errdetails.BadRequest{}
+	appendViolations(br, "", err)
+	return &FieldViolationError{BadRequest: br}
+}
+
+// fieldError is implemented by the validation errors protoc-gen-validate generates.
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// fieldViolation is implemented by the errors of other validators reporting the violation of a field.
+type fieldViolation interface {
+	Field() string
+	Description() string
+}
+
+// appendViolations appends the violations "err" reports to "br", the fields of those of embedded messages
+// being prefixed with "prefix". The errors of multi-errors and of errors joined with errors.Join are
+// appended one by one.
+func appendViolations(br *errdetails.BadRequest, prefix string, err error) {
+	switch multi := err.(type) {
+	case interface{ AllErrors() []error }:
+		for _, err := range multi.AllErrors() {
+			appendViolations(br, prefix, err)
+		}
+		return
+	case interface{ Unwrap() []error }:
+		for _, err := range multi.Unwrap() {
+			appendViolations(br, prefix, err)
+		}
+		return
+	}
+	if violations := protoViolations(err); violations != nil {
+		appendProtoViolations(br, prefix, violations)
+		return
+	}
+	var fv fieldViolation
+	if _, ok := err.(fieldError); !ok && errors.As(err, &fv) {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       prefix + fv.Field(),
+			Description: fv.Description(),
+		})
+		return
+	}
+	fe, ok := err.(fieldError)
+	if !ok {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       strings.TrimSuffix(prefix, "."),
+			Description: err.Error(),
+		})
+		return
+	}
+	if cause := fe.Cause(); cause != nil && fe.Reason() == "embedded message failed validation" {
+		appendViolations(br, prefix+fe.Field()+".", cause)
+		return
+	}
+	br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+		Field:       prefix + fe.Field(),
+		Description: fe.Reason(),
+	})
+}
+
+// protoViolations returns the buf.validate.Violations of "err", or of an error it wraps, if it is an error of
+// protovalidate, whose ToProto method returns them. It returns nil otherwise.
+func protoViolations(err error) protoreflect.Message {
+	for ; err != nil; err = errors.Unwrap(err) {
+		method := reflect.ValueOf(err).MethodByName("ToProto")
+		if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+			continue
+		}
+		msg, ok := method.Call(nil)[0].Interface().(proto.Message)
+		if ok && msg.ProtoReflect().Descriptor().FullName() == "buf.validate.Violations" {
+			return msg.ProtoReflect()
+		}
+	}
+	return nil
+}
+
+// appendProtoViolations appends the buf.validate.Violation of the buf.validate.Violations "violations" to "br",
+// their fields being prefixed with "prefix".
+func appendProtoViolations(br *errdetails.BadRequest, prefix string, violations protoreflect.Message) {
+	fd := violations.Descriptor().Fields().ByName("violations")
+	if fd == nil {
+		return
+	}
+	list := violations.Get(fd).List()
+	for i := 0; i < list.Len(); i++ {
+		v := list.Get(i).Message()
+		// field_path is the deprecated string form of field.
+		path := messageString(v, "field_path")
+		if fd := v.Descriptor().Fields().ByName("field"); fd != nil && v.Has(fd) {
+			path = fieldPathString(v.Get(fd).Message())
+		}
+		field := strings.TrimSuffix(prefix, ".")
+		if path != "" {
+			field = prefix + path
+		}
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: messageString(v, "message"),
+		})
+	}
+}
+
+// fieldPathString returns the buf.validate.FieldPath "path" as protovalidate formats it, e.g. "authors[0].name".
+func fieldPathString(path protoreflect.Message) string {
+	fd := path.Descriptor().Fields().ByName("elements")
+	if fd == nil {
+		return ""
+	}
+	elements := path.Get(fd).List()
+	var b strings.Builder
+	for i := 0; i < elements.Len(); i++ {
+		e := elements.Get(i).Message()
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(messageString(e, "field_name"))
+		subscript := e.Descriptor().Oneofs().ByName("subscript")
+		if subscript == nil {
+			continue
+		}
+		if fd := e.WhichOneof(subscript); fd != nil {
+			if fd.Kind() == protoreflect.StringKind {
+				fmt.Fprintf(&b, "[%q]", e.Get(fd).String())
+			} else {
+				fmt.Fprintf(&b, "[%v]", e.Get(fd).Interface())
+			}
+		}
+	}
+	return b.String()
+}
+
+// messageString returns the string field "name" of "m", or an empty string if it has none.
+func messageString(m protoreflect.Message, name protoreflect.Name) string {
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return m.Get(fd).String()
+}`))
+
+	resourcesTemplate = template.Must(template.New("resources").Parse(`
+// Resource is a resource type declared with google.api.resource, whose names are made of
+// the literal segments and of the IDs of the variables of one of its patterns.
+type Resource struct {
+	// Type is the type of the resource, e.g. "library.googleapis.com/Book".
+	Type string
+	// Patterns are the patterns of the names of the resource, e.g. "publishers/{publisher}/books/{book}".
+	Patterns []string
+}
+
+// Parent returns the resource of the parents of the names of "res", whose patterns are those of "res"
+// without their last collection and ID, e.g. "publishers/{publisher}".
+func (res *Resource) Parent() *Resource {
+	parent := &Resource{Type: res.Type}
+	seen := make(map[string]bool)
+	for _, pattern := range res.Patterns {
+		segments := strings.Split(pattern, "/")
+		if len(segments) <= 2 {
+			continue
+		}
+		p := strings.Join(segments[:len(segments)-2], "/")
+		if !seen[p] {
+			seen[p] = true
+			parent.Patterns = append(parent.Patterns, p)
+		}
+	}
+	return parent
+}
+
+// Parse returns the IDs of "name" keyed by their variables, as matched by the first pattern of "res"
+// matching it. It returns a *ResourceNameError if none does, or if any of its IDs is empty.
+func (res *Resource) Parse(name string) (map[string]string, error) {
+	segments := strings.Split(name, "/")
+	for _, pattern := range res.Patterns {
+		ids, ok := matchResourcePattern(pattern, segments)
+		if !ok {
+			continue
+		}
+		for _, id := range ids {
+			if id == "" {
+				return nil, &ResourceNameError{Type: res.Type, Name: name, Invalid: true}
+			}
+		}
+		return ids, nil
+	}
+	return nil, &ResourceNameError{Type: res.Type, Name: name}
+}
+
+// Format returns the name of "res" with the IDs "ids" keyed by their variables, as formatted by the
+// first pattern of "res" whose variables are exactly those with a non-empty ID. It returns an empty
+// string if there is none.
+func (res *Resource) Format(ids map[string]string) string {
+	var set int
+	for _, id := range ids {
+		if id != "" {
+			set++
+		}
+	}
+	for _, pattern := range res.Patterns {
+		segments := strings.Split(pattern, "/")
+		vars := 0
+		for i, segment := range segments {
+			if !strings.HasPrefix(segment, "{") {
+				continue
+			}
+			vars++
+			segments[i] = ids[strings.Trim(segment, "{}")]
+			if segments[i] == "" {
+				vars = -1
+				break
+			}
+		}
+		if vars == set {
+			return strings.Join(segments, "/")
+		}
+	}
+	return ""
+}
+
+// matchResourcePattern returns the IDs of the name of "segments" keyed by the variables of "pattern",
+// and whether it matches "pattern".
+func matchResourcePattern(pattern string, segments []string) (map[string]string, bool) {
+	parts := strings.Split(pattern, "/")
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+	ids := make(map[string]string)
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") {
+			ids[strings.Trim(part, "{}")] = segments[i]
+		} else if part != segments[i] {
+			return nil, false
+		}
+	}
+	return ids, true
+}
+
+// ResourceNameError is the error of a name which is not a name of its resource. Requests with
+// such a path parameter are rejected with 404 Not Found, unless the name matches a pattern of the
+// resource but has an empty ID, which is rejected with 400 Bad Request.
+type ResourceNameError struct {
+	// Param is the path variable of the parameter holding the name, if any.
+	Param string
+	// Type is the type of the resource.
+	Type string
+	// Name is the name.
+	Name string
+	// Invalid reports whether the name matches a pattern of the resource, but has an empty ID.
+	Invalid bool
+}
+
+func (e *ResourceNameError) Error() string {
+	msg := fmt.Sprintf("%q is not a name of %s", e.Name, e.Type)
+	if e.Invalid {
+		msg = fmt.Sprintf("%q is not a valid name of %s", e.Name, e.Type)
+	}
+	if e.Param != "" {
+		msg = "path parameter " + e.Param + ": " + msg
+	}
+	return msg
+}
+
+// StatusCode makes the request be rejected with 404 Not Found, or 400 Bad Request if the name is invalid.
+func (e *ResourceNameError) StatusCode() int {
+	if e.Invalid {
+		return http.StatusBadRequest
+	}
+	return http.StatusNotFound
+}
+
+// GRPCStatus returns the NotFound status of the error, with the resource as its google.rpc.ResourceInfo
+// detail, or the InvalidArgument status with a google.rpc.BadRequest detail if the name is invalid.
+func (e *ResourceNameError) GRPCStatus() *status.Status {
+	if e.Invalid {
+		st := status.New(codes.InvalidArgument, e.Error())
+		withDetails, err := st.WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: e.Param, Description: e.Error()},
+			},
+		})
+		if err != nil {
+			return st
+		}
+		return withDetails
+	}
+	st := status.New(codes.NotFound, e.Error())
+	withDetails, err := st.WithDetails(&errdetails.ResourceInfo{ResourceType: e.Type, ResourceName: e.Name})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// MarshalJSON returns the google.rpc.Status of GRPCStatus as JSON, so that the error encoders write it
+// as an application/json body.
+func (e *ResourceNameError) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(e.GRPCStatus().Proto())
+}
+
+// CheckResourceName returns a *ResourceNameError if the path variable "param" of "r" is not a name of "res".
+func CheckResourceName(r *http.Request, param string, res *Resource) error {
+	if _, err := res.Parse(PathParam(r, param)); err != nil {
+		e := err.(*ResourceNameError)
+		e.Param = param
+		return e
+	}
+	return nil
+}
+{{range $res := .Resources}}
+// {{$res.Name}}Resource is the resource type {{$res.Type}}.
+var {{$res.Name}}Resource = &Resource{
+	Type: {{printf "%q" $res.Type}},
+	Patterns: []string{ {{- range $res.Patterns}}
+		{{printf "%q" .}},
+	{{- end}}
+	},
+}
+
+// {{$res.Name}}Name is a name of {{$res.Name}}Resource, with the IDs of the variables of its patterns:
+//
+{{- range $res.Patterns}}
+//	{{.}}
+{{- end}}
+type {{$res.Name}}Name struct {
+	{{- range $res.Variables}}
+	{{.Field}} string
+	{{- end}}
+}
+
+// Parse{{$res.Name}}Name parses "name", returning a *ResourceNameError if it is not a name of {{$res.Name}}Resource.
+func Parse{{$res.Name}}Name(name string) ({{$res.Name}}Name, error) {
+	ids, err := {{$res.Name}}Resource.Parse(name)
+	if err != nil {
+		return {{$res.Name}}Name{}, err
+	}
+	return {{$res.Name}}Name{
+		{{- range $res.Variables}}
+		{{.Field}}: ids[{{printf "%q" .Name}}],
+		{{- end}}
+	}, nil
+}
+
+// String returns the name formatted with the first pattern of {{$res.Name}}Resource whose IDs are set in "n",
+// or an empty string if there is none.
+func (n {{$res.Name}}Name) String() string {
+	return {{$res.Name}}Resource.Format(map[string]string{
+		{{- range $res.Variables}}
+		{{printf "%q" .Name}}: n.{{.Field}},
+		{{- end}}
+	})
+}
+{{end}}`))
+
+	corsTemplate = template.Must(template.New("cors").Parse(`
+// CORSPolicy sets how routes answer cross-origin requests.
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to call the routes, e.g. "https://example.com", or "*" for any origin.
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers the callers may send besides the CORS-safelisted ones,
+	// or "*" for any header.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers the callers may read besides the CORS-safelisted ones.
+	ExposedHeaders []string
+	// AllowCredentials allows the callers to send credentials, e.g. cookies.
+	AllowCredentials bool
+	// MaxAge is how long the preflight responses may be cached, in seconds. Browsers pick it if it is 0.
+	MaxAge int
+}
+
+// CORS is the policy of the routes without a gokitmux.cors or gokitmux.service_cors option, and of
+// the preflight requests of the routes of ManualRouter. Those routes answer no cross-origin request
+// if it is nil.
+var CORS {{if .Default}}= {{.Default}}{{else}}*CORSPolicy{{end}}
+{{range $svc := .Services}}
+// {{$svc.Name}}CORS is the policy of the routes of {{$svc.Name}}, set by its gokitmux.service_cors option.
+var {{$svc.Name}}CORS = {{$svc.Policy}}
+{{end}}
+// routeCORS are the policies of the routes whose service or method has a gokitmux option, by their keys.
+var routeCORS = map[string]*CORSPolicy{
+	{{- range $r := .Routes}}
+	{{printf "%q" $r.Key}}: {{$r.Policy}},
+	{{- end}}
+}
+
+// corsPolicy returns the policy of the route with key "key", e.g. "GET /v1/hello/{name}".
+func corsPolicy(key string) *CORSPolicy {
+	if p, ok := routeCORS[key]; ok {
+		return p
+	}
+	return CORS
+}
+
+// allowOrigin sets the headers of the response to a request from "origin" allowing it to read the
+// response, and reports whether the policy allows "origin". It sets nothing if "p" is nil.
+// Browsers reject the credentialed responses allowing "*", so "origin" is echoed instead when the
+// policy allows credentials.
+func (p *CORSPolicy) allowOrigin(h http.Header, origin string) bool {
+	if p == nil || origin == "" {
+		return false
+	}
+	h.Add("Vary", "Origin")
+	allowed := ""
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			allowed = o
+		}
+		if strings.EqualFold(o, origin) {
+			allowed = origin
+			break
+		}
+	}
+	if allowed == "" {
+		return false
+	}
+	if allowed == "*" && p.AllowCredentials {
+		allowed = origin
+	}
+	h.Set("Access-Control-Allow-Origin", allowed)
+	if p.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// corsHandler returns the handler of "route" answering the cross-origin requests with its policy.
+func corsHandler(route *Route) http.Handler {
+	key := route.Method + " " + route.Path
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := corsPolicy(key)
+		if p.allowOrigin(w.Header(), r.Header.Get("Origin")) && len(p.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+		}
+		route.Handler.ServeHTTP(w, r)
+	})
+}
+
+// writePreflight sets the headers of the response to the preflight request "r" of a route with the
+// policy "p", the methods routed for its path being "allowed".
+func writePreflight(w http.ResponseWriter, r *http.Request, p *CORSPolicy, allowed []string) {
+	h := w.Header()
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if !p.allowOrigin(h, r.Header.Get("Origin")) {
+		return
+	}
+	h.Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+	if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" && len(p.AllowedHeaders) > 0 {
+		allowedHeaders := strings.Join(p.AllowedHeaders, ", ")
+		for _, header := range p.AllowedHeaders {
+			if header == "*" {
+				allowedHeaders = headers
+				break
+			}
+		}
+		h.Set("Access-Control-Allow-Headers", allowedHeaders)
+	}
+	if p.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+	}
+}`))
+
+	paramsTemplate = template.Must(template.New("params").Parse(`
+// HeaderValue returns the first value of the header "name" of "r", and whether it is present.
+func HeaderValue(r *http.Request, name string) (string, bool) {
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// CookieValue returns the value of the cookie "name" of "r", and whether it is present.
+func CookieValue(r *http.Request, name string) (string, bool) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	return c.Value, true
+}`))
+
+	transportTemplate = template.Must(template.New("transport").Parse(`
+// MethodFunc calls a method of the gateway with the decoded request.
+type MethodFunc func(ctx context.Context, request interface{}) (response interface{}, err error)
+
+// DecodeRequestFunc extracts the request message from an HTTP request.
+type DecodeRequestFunc func(context.Context, *http.Request) (request interface{}, err error)
+
+// EncodeResponseFunc writes the response message to an HTTP response.
+type EncodeResponseFunc func(context.Context, http.ResponseWriter, interface{}) error
+
+// ErrorEncoder writes an error to an HTTP response.
+type ErrorEncoder func(ctx context.Context, err error, w http.ResponseWriter)
+
+// StatusCoder is implemented by errors encoded with a HTTP status code other than 500 by DefaultErrorEncoder.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Headerer is implemented by errors adding headers to the response in DefaultErrorEncoder.
+type Headerer interface {
+	Headers() http.Header
+}
+
+// Handler serves a method of the gateway: it decodes the request, calls the method and encodes
+// the response or the error.
+type Handler struct {
+	method       MethodFunc
+	decode       DecodeRequestFunc
+	encode       EncodeResponseFunc
+	errorEncoder ErrorEncoder
+}
+
+// NewHandler returns a Handler calling "method". DefaultErrorEncoder is used if "errorEncoder" is nil.
+func NewHandler(method MethodFunc, decode DecodeRequestFunc, encode EncodeResponseFunc, errorEncoder ErrorEncoder) *Handler {
+	if errorEncoder == nil {
+		errorEncoder = DefaultErrorEncoder
+	}
+	return &Handler{
+		method:       method,
+		decode:       decode,
+		encode:       encode,
+		errorEncoder: errorEncoder,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+// The ServerMetadata in the context is written to the response before the response is, as go-kit's
+// ServerAfter functions are. Errors are written without it.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := PopulateContext(r.Context(), r)
+
+	request, err := h.decode(ctx, r)
+	if err != nil {
+		h.errorEncoder(ctx, err, w)
+		return
+	}
+
+	response, err := h.method(ctx, request)
+	if err != nil {
+		h.errorEncoder(ctx, err, w)
+		return
+	}
+
+	ctx = WriteServerMetadata(ctx, w)
+
+	if err := h.encode(ctx, w, response); err != nil {
+		h.errorEncoder(ctx, err, w)
+	}
+}
+
+// DefaultErrorEncoder writes "err" as plain text, or as JSON if it implements json.Marshaler.
+// The status code is 500 unless "err" implements StatusCoder, and headers are added if it implements Headerer.
+func DefaultErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+	contentType, body := "text/plain; charset=utf-8", []byte(err.Error())
+	if marshaler, ok := err.(json.Marshaler); ok {
+		if jsonBody, marshalErr := marshaler.MarshalJSON(); marshalErr == nil {
+			contentType, body = "application/json; charset=utf-8", jsonBody
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	if headerer, ok := err.(Headerer); ok {
+		for k, values := range headerer.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+	code := http.StatusInternalServerError
+	if sc, ok := err.(StatusCoder); ok {
+		code = sc.StatusCode()
+	}
+	w.WriteHeader(code)
+	w.Write(body)
+}`))
+
+	grpcTemplate = template.Must(template.New("grpc").Parse(`
+// SetResponseMetadata adds the header and trailer of a gRPC response to the ServerMetadata in the context,
+// prefixed with MetadataHeaderPrefix and MetadataTrailerPrefix.
+func SetResponseMetadata(ctx context.Context, header, trailer metadata.MD) {
+	md, ok := ServerMetadataFromContext(ctx)
+	if !ok {
+		return
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			md.Header.Add(MetadataHeaderPrefix+k, v)
+		}
+	}
+	for k, vs := range trailer {
+		for _, v := range vs {
+			md.Trailer.Add(MetadataTrailerPrefix+k, v)
+		}
+	}
+}
+
+// ServeUnary serves a unary gRPC method in-process. It runs "handler" through "interceptors", in order,
+// with the headers of the HTTP request being served selected by "matcher" as incoming metadata,
+// or by IncomingHeaderMatcher if it is nil.
+// The header and trailer set by the method are added to the ServerMetadata in the context.
+func ServeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	matcher HeaderMatcher, interceptors ...grpc.UnaryServerInterceptor) (interface{}, error) {
+	if matcher == nil {
+		matcher = IncomingHeaderMatcher
+	}
+	ctx = metadata.NewIncomingContext(ctx, RequestMetadata(ctx, matcher))
+	stream := &serverTransportStream{method: info.FullMethod}
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	h := handler
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], h
+		h = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	resp, err := h(ctx, req)
+	SetResponseMetadata(ctx, stream.header, stream.trailer)
+	if err != nil {
+		return nil, GRPCError(err)
+	}
+	return resp, nil
+}
+
+// serverTransportStream collects the header and trailer set with grpc.SetHeader and grpc.SetTrailer
+// by methods served by ServeUnary.
+type serverTransportStream struct {
+	method string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *serverTransportStream) Method() string {
+	return s.method
+}
+
+func (s *serverTransportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *serverTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *serverTransportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// GRPCError wraps a gRPC status error, so that it is encoded with the HTTP status code of its code.
+// Other errors are returned as they are.
+func GRPCError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return &grpcStatusError{s: s}
+}
+
+type grpcStatusError struct {
+	s *status.Status
+}
+
+func (e *grpcStatusError) Error() string {
+	return e.s.Message()
+}
+
+// GRPCStatus returns the gRPC status of the error.
+func (e *grpcStatusError) GRPCStatus() *status.Status {
+	return e.s
+}
+
+// StatusCode implements the StatusCoder interface of go-kit.
+func (e *grpcStatusError) StatusCode() int {
+	return HTTPStatusFromCode(e.s.Code())
+}
+
+// HTTPStatusFromCode returns the HTTP status code corresponding to a gRPC code.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}`))
+
+	adaptersTemplate = template.Must(template.New("adapters").Funcs(funcs).Parse(`
+{{$PackageName := .PackageName}}
+{{- range $svc := .Services}}
+{{$service := "GatewayService"}}{{if $.SplitServices}}{{$service = ServiceName $svc.Service}}{{end}}
+{{- if $.GRPCClient}}
+// {{$svc.GetName}}ClientAdapter implements the {{$svc.GetName}} methods of {{$PackageName}}.{{$service}}
+// by forwarding them to a gRPC {{$svc.GetName}}Client, proxying the gateway to a gRPC backend.
+type {{$svc.GetName}}ClientAdapter struct {
+	{{- if $.RequireUnimplemented}}
+	{{$PackageName}}.Unimplemented{{$service}}
+	{{end}}
+	Client {{PackageRef $svc.File.GoPkg}}.{{$svc.GetName}}Client
+	// HeaderMatcher selects the HTTP request headers sent as outgoing metadata.
+	// {{$PackageName}}.IncomingHeaderMatcher is used if it is nil.
+	HeaderMatcher {{$PackageName}}.HeaderMatcher
+}
+{{if $.SplitServices}}
+var _ {{$PackageName}}.{{$service}} = (*{{$svc.GetName}}ClientAdapter)(nil)
+{{end}}
+// New{{$svc.GetName}}ClientAdapter returns a {{$svc.GetName}}ClientAdapter forwarding to "client".
+func New{{$svc.GetName}}ClientAdapter(client {{PackageRef $svc.File.GoPkg}}.{{$svc.GetName}}Client) *{{$svc.GetName}}ClientAdapter {
+	return &{{$svc.GetName}}ClientAdapter{Client: client}
+}
+
+func (a *{{$svc.GetName}}ClientAdapter) outgoingContext(ctx context.Context) context.Context {
+	matcher := a.HeaderMatcher
+	if matcher == nil {
+		matcher = {{$PackageName}}.IncomingHeaderMatcher
+	}
+	md := {{$PackageName}}.RequestMetadata(ctx, matcher)
+	if out, ok := metadata.FromOutgoingContext(ctx); ok {
+		md = metadata.Join(out, md)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+{{range $m := $svc.Methods}}
+{{- if or $m.GetClientStreaming $m.GetServerStreaming}}
+// {{$m.GetName}} is not forwarded, as streaming methods are not supported.
+func (a *{{$svc.GetName}}ClientAdapter) {{$m.GetName}}(context.Context, *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	return nil, &{{$PackageName}}.UnimplementedError{Method: {{$m.GetName | printf "%q"}}}
+}
+{{- else}}
+// {{$m.GetName}} forwards to {{$m.GetName}} of the client.
+func (a *{{$svc.GetName}}ClientAdapter) {{$m.GetName}}(ctx context.Context, req *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	var header, trailer metadata.MD
+	resp, err := a.Client.{{$m.GetName}}(a.outgoingContext(ctx), req, grpc.Header(&header), grpc.Trailer(&trailer))
+	{{$PackageName}}.SetResponseMetadata(ctx, header, trailer)
+	if err != nil {
+		return nil, {{$PackageName}}.GRPCError(err)
+	}
+	return resp, nil
+}
+{{- end}}
+{{end}}
+{{- end}}
+{{- if $.GRPCServer}}
+// {{$svc.GetName}}ServerAdapter implements the {{$svc.GetName}} methods of {{$PackageName}}.{{$service}}
+// by calling a gRPC {{$svc.GetName}}Server in-process, so that it serves both gRPC and the gateway.
+type {{$svc.GetName}}ServerAdapter struct {
+	{{- if $.RequireUnimplemented}}
+	{{$PackageName}}.Unimplemented{{$service}}
+	{{end}}
+	Server {{PackageRef $svc.File.GoPkg}}.{{$svc.GetName}}Server
+	// Interceptors are run around the methods of Server, in order.
+	Interceptors []grpc.UnaryServerInterceptor
+	// HeaderMatcher selects the HTTP request headers passed as incoming metadata.
+	// {{$PackageName}}.IncomingHeaderMatcher is used if it is nil.
+	HeaderMatcher {{$PackageName}}.HeaderMatcher
+}
+{{if $.SplitServices}}
+var _ {{$PackageName}}.{{$service}} = (*{{$svc.GetName}}ServerAdapter)(nil)
+{{end}}
+// New{{$svc.GetName}}ServerAdapter returns a {{$svc.GetName}}ServerAdapter calling "srv" through "interceptors".
+func New{{$svc.GetName}}ServerAdapter(srv {{PackageRef $svc.File.GoPkg}}.{{$svc.GetName}}Server, interceptors ...grpc.UnaryServerInterceptor) *{{$svc.GetName}}ServerAdapter {
+	return &{{$svc.GetName}}ServerAdapter{Server: srv, Interceptors: interceptors}
+}
+{{range $m := $svc.Methods}}
+{{- if or $m.GetClientStreaming $m.GetServerStreaming}}
+// {{$m.GetName}} is not served, as streaming methods are not supported.
+func (a *{{$svc.GetName}}ServerAdapter) {{$m.GetName}}(context.Context, *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	return nil, &{{$PackageName}}.UnimplementedError{Method: {{$m.GetName | printf "%q"}}}
+}
+{{- else}}
+// {{$m.GetName}} calls {{$m.GetName}} of the server.
+func (a *{{$svc.GetName}}ServerAdapter) {{$m.GetName}}(ctx context.Context, req *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	info := &grpc.UnaryServerInfo{
+		Server:     a.Server,
+		FullMethod: "/{{with $svc.File.GetPackage}}{{.}}.{{end}}{{$svc.GetName}}/{{$m.GetName}}",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return a.Server.{{$m.GetName}}(ctx, req.(*{{$m.RequestType}}))
+	}
+	resp, err := {{$PackageName}}.ServeUnary(ctx, req, info, handler, a.HeaderMatcher, a.Interceptors...)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*{{$m.ResponseType}}), nil
+}
+{{- end}}
+{{end}}
+{{- end}}
+{{- end}}`))
+
+	fakeTemplate = template.Must(template.New("fake").Funcs(funcs).Parse(`
+{{$PackageName := .PackageName}}
+var _ {{$PackageName}}.GatewayService = (*GatewayService)(nil)
+
+// Call is a call received by a fake.
+type Call struct {
+	// Method is the name of the called method.
+	Method string
+	// Request is the request the method was called with.
+	Request proto.Message
+}
+
+// GatewayService is a fake {{$PackageName}}.GatewayService recording the calls of its methods.
+//
+// A method calls its function field when it is set. Otherwise it returns DefaultErr,
+// or an empty response if DefaultErr is nil.
+// Methods of {{$PackageName}}.GatewayService not routed by the gateway are left to the embedded GatewayService.
+type GatewayService struct {
+	{{$PackageName}}.GatewayService
+	{{range $m := .Methods}}
+	{{$m.GetName}}Func func(context.Context, *{{$m.RequestType}}) (*{{$m.ResponseType}}, error)
+	{{- end}}
+
+	// DefaultErr is returned by the methods without a function field set.
+	DefaultErr error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (f *GatewayService) record(method string, req proto.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: method, Request: req})
+}
+
+// Calls returns the calls received so far, in order.
+func (f *GatewayService) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+{{range $m := .Methods}}
+func (f *GatewayService) {{$m.GetName}}(ctx context.Context, req *{{$m.RequestType}}) (*{{$m.ResponseType}}, error) {
+	f.record({{$m.GetName | printf "%q"}}, req)
+	if f.{{$m.GetName}}Func != nil {
+		return f.{{$m.GetName}}Func(ctx, req)
+	}
+	if f.DefaultErr != nil {
+		return nil, f.DefaultErr
+	}
+	return &{{$m.ResponseType}}{}, nil
+}
+
+// {{$m.GetName}}Calls returns the requests {{$m.GetName}} received so far, in order.
+func (f *GatewayService) {{$m.GetName}}Calls() []*{{$m.RequestType}} {
+	var result []*{{$m.RequestType}}
+	for _, c := range f.Calls() {
+		if c.Method == {{$m.GetName | printf "%q"}} {
+			result = append(result, c.Request.(*{{$m.RequestType}}))
+		}
+	}
+	return result
+}
+{{end}}`))
+
 	endpointsTemplate = template.Must(template.New("kit").Funcs(funcs).Parse(`
+{{- if .SplitServices}}
+{{- range $svc := .Services}}
+{{$name := ServiceName $svc}}
+type {{$name}}Endpointer interface {
+	{{- if eq $.Transport "nethttp"}}
+	Register({{$name}}) *Route
+	{{- else}}
+	Register({{$name}}, *Endpoints) *Route
+	{{- end}}
+	Encode(context.Context, http.ResponseWriter, interface{}) error
+	Decode(context.Context, *http.Request) (interface{}, error)
+	ForHandler(handler http.Handler) http.Handler
+}
+{{- if ne $.Transport "nethttp"}}
+
+// {{$name}}Maker is implemented by the handlers with a hand-written Make method. Their route serves
+// the endpoint it returns instead of the one of the endpoint set of the service.
+type {{$name}}Maker interface {
+	Make({{$name}}) endpoint.Endpoint
+}
+{{- end}}
+
+var {{$name}}Handlers []{{$name}}Endpointer
+
+func Register{{$name}}Handler(h {{$name}}Endpointer) {
+	{{$name}}Handlers = append({{$name}}Handlers, h)
+}
+{{- end}}
+{{- else}}
 type Endpointer interface {
+	{{- if eq .Transport "nethttp"}}
 	Register(GatewayService) *Route
-	Make(GatewayService) endpoint.Endpoint
+	{{- else}}
+	Register(GatewayService, *Endpoints) *Route
+	{{- end}}
 	Encode(context.Context, http.ResponseWriter, interface{}) error
 	Decode(context.Context, *http.Request) (interface{}, error)
 	ForHandler(handler http.Handler) http.Handler
 }
+{{- if ne .Transport "nethttp"}}
+
+// Maker is implemented by the handlers with a hand-written Make method. Their route serves
+// the endpoint it returns instead of the one of the endpoint set of the service.
+type Maker interface {
+	Make(GatewayService) endpoint.Endpoint
+}
+{{- end}}
+{{- end}}
 
 type Route struct {
 	Path    string
 	Handler http.Handler
 	Method string
 	Name   string
+	{{- if eq .Router "chi"}}
+	// Wildcard is the path variable bound to the trailing "*" of Path, if any.
+	Wildcard string
+	{{- end}}
+}
+{{- if ne .Transport "nethttp"}}
+
+// Endpoints are shared by the routes registered on a router: the routes of a service serve the
+// endpoints of a single endpoint set, wrapped with Middleware.
+type Endpoints struct {
+	// Middleware wraps the endpoint of each route, the first one outermost.
+	Middleware []endpoint.Middleware
+
+	sets map[string]interface{}
+}
+
+// NewEndpoints returns the Endpoints of the routes of a router, wrapped with "middleware".
+func NewEndpoints(middleware ...endpoint.Middleware) *Endpoints {
+	return &Endpoints{Middleware: middleware, sets: make(map[string]interface{})}
 }
 
+// Set returns the endpoint set of the service "name", built with "build" by the first route of the service.
+func (e *Endpoints) Set(name string, build func() interface{}) interface{} {
+	set, ok := e.sets[name]
+	if !ok {
+		set = build()
+		e.sets[name] = set
+	}
+	return set
+}
+
+// Wrap returns "ep" wrapped with Middleware.
+func (e *Endpoints) Wrap(ep endpoint.Endpoint) endpoint.Endpoint {
+	for i := len(e.Middleware) - 1; i >= 0; i-- {
+		ep = e.Middleware[i](ep)
+	}
+	return ep
+}
+{{- end}}
+{{- if not .SplitServices}}
+
 var Handlers []Endpointer
 
 func RegisterHandler(h Endpointer) {
 	Handlers = append(Handlers, h)
-}`))
+}
+{{- end}}`))
 )
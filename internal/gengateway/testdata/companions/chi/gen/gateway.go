@@ -0,0 +1,9 @@
+package gen
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func ManualRouter(svc GatewayService, r chi.Router) chi.Router {
+	return r
+}
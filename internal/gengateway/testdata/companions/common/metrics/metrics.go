@@ -0,0 +1,11 @@
+// Package metrics is the metrics package passed to the generator with the metrics parameter.
+package metrics
+
+import (
+	"net/http"
+)
+
+// ForHandler tracks the requests served by "h" as "name".
+func ForHandler(h http.Handler, name string) http.Handler {
+	return h
+}
@@ -0,0 +1,84 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package bye
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// FarewellClient is the client API for Farewell service.
+type FarewellClient interface {
+	SayBye(ctx context.Context, in *ByeRequest, opts ...grpc.CallOption) (*ByeReply, error)
+}
+
+type farewellClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFarewellClient(cc grpc.ClientConnInterface) FarewellClient {
+	return &farewellClient{cc}
+}
+
+func (c *farewellClient) SayBye(ctx context.Context, in *ByeRequest, opts ...grpc.CallOption) (*ByeReply, error) {
+	out := new(ByeReply)
+	err := c.cc.Invoke(ctx, "/bye.Farewell/SayBye", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FarewellServer is the server API for Farewell service.
+type FarewellServer interface {
+	SayBye(context.Context, *ByeRequest) (*ByeReply, error)
+	mustEmbedUnimplementedFarewellServer()
+}
+
+// UnimplementedFarewellServer must be embedded to have forward compatible implementations.
+type UnimplementedFarewellServer struct {
+}
+
+func (UnimplementedFarewellServer) SayBye(context.Context, *ByeRequest) (*ByeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SayBye not implemented")
+}
+func (UnimplementedFarewellServer) mustEmbedUnimplementedFarewellServer() {}
+
+func RegisterFarewellServer(s grpc.ServiceRegistrar, srv FarewellServer) {
+	s.RegisterService(&Farewell_ServiceDesc, srv)
+}
+
+func _Farewell_SayBye_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ByeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FarewellServer).SayBye(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/bye.Farewell/SayBye",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FarewellServer).SayBye(ctx, req.(*ByeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Farewell_ServiceDesc is the grpc.ServiceDesc for Farewell service.
+var Farewell_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bye.Farewell",
+	HandlerType: (*FarewellServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SayBye",
+			Handler:    _Farewell_SayBye_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bye/bye.proto",
+}
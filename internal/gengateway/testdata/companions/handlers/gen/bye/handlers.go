@@ -0,0 +1,26 @@
+package bye
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"example.com/gmtest/gen"
+	pb "example.com/gmtest/pb/bye"
+)
+
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func (e *SayBye) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	return &pb.ByeRequest{Name: gen.PathParam(r, "name"), Reason: r.URL.Query().Get("reason")}, nil
+}
+
+func (e *SayBye) Encode(_ context.Context, w http.ResponseWriter, resp interface{}) error {
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func (e *SayBye) ForHandler(h http.Handler) http.Handler {
+	return h
+}
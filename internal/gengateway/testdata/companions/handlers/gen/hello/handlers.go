@@ -0,0 +1,73 @@
+package hello
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"example.com/gmtest/gen"
+	pb "example.com/gmtest/pb/hello"
+)
+
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *SayHello) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.HelloRequest{}
+	if err := gen.DecodePath(r, req, "name"); err != nil {
+		return nil, err
+	}
+	if err := gen.DecodeQuery(r, req, filter_SayHello); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *SayHello) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return encode(ctx, w, resp)
+}
+
+func (e *SayHello) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+// Make predates the endpoint sets; the route serves its endpoint instead of the one of GreeterSet.
+func (e *SayHello1) Make(svc gen.GatewayService) endpoint.Endpoint {
+	return MakeSayHelloEndpoint(svc)
+}
+
+var _ gen.Maker = (*SayHello1)(nil)
+
+func (e *SayHello1) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	return &pb.HelloRequest{Name: gen.PathParam(r, "name"), Lang: gen.PathParam(r, "lang")}, nil
+}
+
+func (e *SayHello1) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return encode(ctx, w, resp)
+}
+
+func (e *SayHello1) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *CreateGreeting) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.CreateGreetingRequest{Greeting: &pb.Greeting{}}
+	if err := gen.DecodeMessage(r, req.Greeting); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *CreateGreeting) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return encode(ctx, w, resp)
+}
+
+func (e *CreateGreeting) ForHandler(h http.Handler) http.Handler {
+	return h
+}
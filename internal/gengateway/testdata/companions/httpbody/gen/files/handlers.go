@@ -0,0 +1,96 @@
+package files
+
+import (
+	"context"
+	"mime"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/api/httpbody"
+
+	"example.com/gmtest/gen"
+	pb "example.com/gmtest/pb/files"
+)
+
+func (e *Upload) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.UploadRequest{Name: gen.PathParam(r, "name"), File: &httpbody.HttpBody{}}
+	if err := gen.DecodeMessage(r, req.File); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *Upload) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *Upload) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *Download) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	return &pb.DownloadRequest{Name: gen.PathParam(r, "name")}, nil
+}
+
+func (e *Download) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *Download) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *Attach) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.AttachRequest{}
+	if err := gen.DecodeMessage(r, req); err != nil {
+		return nil, err
+	}
+	req.Name = gen.PathParam(r, "name")
+	return req, nil
+}
+
+func (e *Attach) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *Attach) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+// Decode leaves the files of multipart/form-data bodies to be streamed by the service.
+func (e *Import) Decode(ctx context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.AttachRequest{}
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		err = gen.StreamMultipart(ctx, r, req)
+	} else {
+		err = gen.DecodeMessage(r, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *Import) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *Import) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *Echo) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &httpbody.HttpBody{}
+	if err := gen.DecodeMessage(r, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *Echo) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *Echo) ForHandler(h http.Handler) http.Handler {
+	return h
+}
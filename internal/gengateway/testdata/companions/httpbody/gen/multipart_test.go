@@ -0,0 +1,146 @@
+package gen_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"example.com/gmtest/gen"
+	"example.com/gmtest/gen/fake"
+	"example.com/gmtest/pb/files"
+)
+
+// newMultipartRequest returns a multipart/form-data request to "path" with the form field title followed by
+// the file content. The length of the body is unknown if "streamed" is set.
+func newMultipartRequest(t *testing.T, path, title, content string, streamed bool) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	if err := mw.WriteField("title", title); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := mw.CreateFormFile("content", "content.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(fw, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var r *http.Request
+	if streamed {
+		r = httptest.NewRequest("POST", path, io.MultiReader(body))
+	} else {
+		r = httptest.NewRequest("POST", path, body)
+	}
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+// limitMultipart sets gen.Multipart.MaxSize to "size" for the duration of the test.
+func limitMultipart(t *testing.T, size int64) {
+	saved := gen.Multipart
+	gen.Multipart = &gen.MultipartOptions{MaxSize: size, MaxMemory: saved.MaxMemory}
+	t.Cleanup(func() { gen.Multipart = saved })
+}
+
+func TestAttachMultipart(t *testing.T) {
+	svc := &fake.GatewayService{}
+	w := httptest.NewRecorder()
+	newTestRouter(svc).ServeHTTP(w, newMultipartRequest(t, "/v1/attachments/dune", "Dune", "chapter one", false))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /v1/attachments/dune responded %d; want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	calls := svc.AttachCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Attach was called %d times; want 1", len(calls))
+	}
+	got, want := calls[0], &files.AttachRequest{Name: "dune", Title: "Dune", Content: []byte("chapter one")}
+	if !proto.Equal(got, want) {
+		t.Errorf("Attach received %v; want %v", got, want)
+	}
+}
+
+func TestAttachMultipartTooLarge(t *testing.T) {
+	limitMultipart(t, 64)
+	for _, streamed := range []bool{false, true} {
+		svc := &fake.GatewayService{}
+		w := httptest.NewRecorder()
+		r := newMultipartRequest(t, "/v1/attachments/dune", "Dune", string(make([]byte, 256)), streamed)
+		newTestRouter(svc).ServeHTTP(w, r)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("POST /v1/attachments/dune (streamed: %t) responded %d; want %d: %s", streamed, w.Code, http.StatusRequestEntityTooLarge, w.Body)
+		}
+		if calls := svc.AttachCalls(); len(calls) != 0 {
+			t.Errorf("Attach (streamed: %t) was called %d times; want 0", streamed, len(calls))
+		}
+	}
+}
+
+// streamContent returns an ImportFunc reading the files left by gen.StreamMultipart into "content".
+func streamContent(content *bytes.Buffer) func(context.Context, *files.AttachRequest) (*files.DownloadRequest, error) {
+	return func(ctx context.Context, req *files.AttachRequest) (*files.DownloadRequest, error) {
+		stream, ok := gen.MultipartStreamFromContext(ctx)
+		if !ok {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for {
+			part, err := stream.NextPart()
+			if err == io.EOF {
+				return &files.DownloadRequest{Name: req.GetTitle()}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(content, part); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func TestImportMultipart(t *testing.T) {
+	var content bytes.Buffer
+	svc := &fake.GatewayService{ImportFunc: streamContent(&content)}
+	w := httptest.NewRecorder()
+	newTestRouter(svc).ServeHTTP(w, newMultipartRequest(t, "/v1/imports", "Dune", "chapter one", true))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /v1/imports responded %d; want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	calls := svc.ImportCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Import was called %d times; want 1", len(calls))
+	}
+	// the files are left to the service, unlike the fields preceding them.
+	if got, want := calls[0], (&files.AttachRequest{Title: "Dune"}); !proto.Equal(got, want) {
+		t.Errorf("Import received %v; want %v", got, want)
+	}
+	if got, want := content.String(), "chapter one"; got != want {
+		t.Errorf("Import streamed %q; want %q", got, want)
+	}
+}
+
+func TestImportMultipartTooLarge(t *testing.T) {
+	limitMultipart(t, 512)
+	var content bytes.Buffer
+	svc := &fake.GatewayService{ImportFunc: streamContent(&content)}
+	w := httptest.NewRecorder()
+	newTestRouter(svc).ServeHTTP(w, newMultipartRequest(t, "/v1/imports", "Dune", string(make([]byte, 1024)), true))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST /v1/imports responded %d; want %d: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body)
+	}
+	if calls := svc.ImportCalls(); len(calls) != 1 {
+		t.Errorf("Import was called %d times; want 1, the fields preceding the file being within the limit", len(calls))
+	}
+}
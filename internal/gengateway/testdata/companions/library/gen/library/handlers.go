@@ -0,0 +1,136 @@
+package library
+
+import (
+	"context"
+	"net/http"
+
+	"example.com/gmtest/gen"
+	pb "example.com/gmtest/pb/library"
+)
+
+func (e *GetBook) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.GetBookRequest{}
+	if err := gen.DecodePath(r, req, "name"); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *GetBook) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *GetBook) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *GetBook1) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.GetBookRequest{}
+	if err := gen.DecodePath(r, req, "name"); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *GetBook1) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *GetBook1) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *ListBooks) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.ListBooksRequest{}
+	if err := gen.DecodePath(r, req, "parent"); err != nil {
+		return nil, err
+	}
+	if err := gen.DecodeQuery(r, req, filter_ListBooks); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *ListBooks) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *ListBooks) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *CreateBook) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.CreateBookRequest{Book: &pb.Book{}}
+	if err := gen.DecodeMessage(r, req.Book); err != nil {
+		return nil, err
+	}
+	if err := gen.DecodeQuery(r, req, filter_CreateBook); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *CreateBook) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *CreateBook) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *UpdateBook) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.UpdateBookRequest{Book: &pb.Book{}}
+	if err := gen.DecodeMessage(r, req.Book); err != nil {
+		return nil, err
+	}
+	if err := gen.DecodePath(r, req, "book.name"); err != nil {
+		return nil, err
+	}
+	if err := gen.DecodeQuery(r, req, filter_UpdateBook); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *UpdateBook) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *UpdateBook) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *DeleteBook) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.DeleteBookRequest{}
+	if err := gen.DecodePath(r, req, "name"); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *DeleteBook) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *DeleteBook) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *ArchiveBook) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.ArchiveBookRequest{}
+	if err := gen.DecodeMessage(r, req); err != nil {
+		return nil, err
+	}
+	if err := gen.DecodePath(r, req, "name"); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *ArchiveBook) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return gen.EncodeMessage(ctx, w, resp)
+}
+
+func (e *ArchiveBook) ForHandler(h http.Handler) http.Handler {
+	return h
+}
@@ -0,0 +1,9 @@
+package gen
+
+import (
+	"github.com/gorilla/mux"
+)
+
+func ManualRouter(svc GatewayService, r *mux.Router) *mux.Router {
+	return r
+}
@@ -0,0 +1,58 @@
+package hello
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"example.com/gmtest/gen"
+	pb "example.com/gmtest/pb/hello"
+)
+
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	gen.DefaultErrorEncoder(ctx, err, w)
+}
+
+func encode(_ context.Context, w http.ResponseWriter, resp interface{}) error {
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func (e *SayHello) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	return &pb.HelloRequest{Name: gen.PathParam(r, "name"), Lang: r.URL.Query().Get("lang")}, nil
+}
+
+func (e *SayHello) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return encode(ctx, w, resp)
+}
+
+func (e *SayHello) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *SayHello1) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	return &pb.HelloRequest{Name: gen.PathParam(r, "name"), Lang: gen.PathParam(r, "lang")}, nil
+}
+
+func (e *SayHello1) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return encode(ctx, w, resp)
+}
+
+func (e *SayHello1) ForHandler(h http.Handler) http.Handler {
+	return h
+}
+
+func (e *CreateGreeting) Decode(_ context.Context, r *http.Request) (interface{}, error) {
+	req := &pb.CreateGreetingRequest{Greeting: &pb.Greeting{}}
+	if err := json.NewDecoder(r.Body).Decode(req.Greeting); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (e *CreateGreeting) Encode(ctx context.Context, w http.ResponseWriter, resp interface{}) error {
+	return encode(ctx, w, resp)
+}
+
+func (e *CreateGreeting) ForHandler(h http.Handler) http.Handler {
+	return h
+}
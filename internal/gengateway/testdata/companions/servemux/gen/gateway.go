@@ -0,0 +1,9 @@
+package gen
+
+import (
+	"net/http"
+)
+
+func ManualRouter(svc GatewayService, r *http.ServeMux) *http.ServeMux {
+	return r
+}
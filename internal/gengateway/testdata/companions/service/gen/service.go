@@ -0,0 +1,14 @@
+package gen
+
+import (
+	"context"
+
+	"example.com/gmtest/pb/bye"
+	"example.com/gmtest/pb/hello"
+)
+
+type GatewayService interface {
+	SayHello(context.Context, *hello.HelloRequest) (*hello.HelloReply, error)
+	CreateGreeting(context.Context, *hello.CreateGreetingRequest) (*hello.Greeting, error)
+	SayBye(context.Context, *bye.ByeRequest) (*bye.ByeReply, error)
+}
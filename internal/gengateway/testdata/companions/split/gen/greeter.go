@@ -0,0 +1,18 @@
+package gen
+
+import (
+	"context"
+
+	"example.com/gmtest/pb/hello"
+)
+
+// greeter is a deployable only serving GreeterService.
+type greeter struct {
+	UnimplementedGreeterService
+}
+
+func (greeter) SayHello(_ context.Context, req *hello.HelloRequest) (*hello.HelloReply, error) {
+	return &hello.HelloReply{Message: "Hello " + req.GetName()}, nil
+}
+
+var _ GreeterService = greeter{}
@@ -0,0 +1,18 @@
+package gen
+
+import (
+	"context"
+
+	"example.com/gmtest/pb/hello"
+)
+
+// greeter only implements SayHello, leaving the other methods to UnimplementedGatewayService.
+type greeter struct {
+	UnimplementedGatewayService
+}
+
+func (greeter) SayHello(_ context.Context, req *hello.HelloRequest) (*hello.HelloReply, error) {
+	return &hello.HelloReply{Message: "Hello " + req.GetName()}, nil
+}
+
+var _ GatewayService = greeter{}
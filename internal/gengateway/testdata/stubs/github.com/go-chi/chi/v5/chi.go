@@ -0,0 +1,80 @@
+// Package chi is a stub of github.com/go-chi/chi/v5 with its exported API.
+package chi
+
+import (
+	"context"
+	"net/http"
+)
+
+// Router consisting of the core routing methods used by chi's Mux.
+type Router interface {
+	http.Handler
+
+	// Use appends one or more middlewares onto the Router stack.
+	Use(middlewares ...func(http.Handler) http.Handler)
+	// Route mounts a sub-Router along a pattern string.
+	Route(pattern string, fn func(r Router)) Router
+	// Mount attaches another http.Handler along ./pattern/*
+	Mount(pattern string, h http.Handler)
+	// Handle adds a route for all http methods.
+	Handle(pattern string, h http.Handler)
+	// HandleFunc adds a route for all http methods.
+	HandleFunc(pattern string, h http.HandlerFunc)
+	// Method adds a route for the http method.
+	Method(method, pattern string, h http.Handler)
+	// MethodFunc adds a route for the http method.
+	MethodFunc(method, pattern string, h http.HandlerFunc)
+	// NotFound defines a handler to respond whenever a route could not be found.
+	NotFound(h http.HandlerFunc)
+	// MethodNotAllowed defines a handler to respond whenever a method is not allowed.
+	MethodNotAllowed(h http.HandlerFunc)
+	// Match searches the routing tree for a handler that matches the method/path.
+	Match(rctx *Context, method, path string) bool
+}
+
+// Mux is a simple HTTP route multiplexer.
+type Mux struct{}
+
+// NewRouter returns a new Mux object that implements the Router interface.
+func NewRouter() *Mux { return &Mux{} }
+
+func (mx *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request)      {}
+func (mx *Mux) Use(middlewares ...func(http.Handler) http.Handler)    {}
+func (mx *Mux) Route(pattern string, fn func(r Router)) Router        { return mx }
+func (mx *Mux) Mount(pattern string, h http.Handler)                  {}
+func (mx *Mux) Handle(pattern string, h http.Handler)                 {}
+func (mx *Mux) HandleFunc(pattern string, h http.HandlerFunc)         {}
+func (mx *Mux) Method(method, pattern string, h http.Handler)         {}
+func (mx *Mux) MethodFunc(method, pattern string, h http.HandlerFunc) {}
+func (mx *Mux) NotFound(h http.HandlerFunc)                           {}
+func (mx *Mux) MethodNotAllowed(h http.HandlerFunc)                   {}
+func (mx *Mux) Match(rctx *Context, method, path string) bool         { return false }
+
+// Context is the default routing context set on the root node of a request context.
+type Context struct {
+	// URLParams are the stack of routeParams captured during the routing lifecycle.
+	URLParams RouteParams
+}
+
+// NewRouteContext returns a new routing Context object.
+func NewRouteContext() *Context { return &Context{} }
+
+// URLParam returns the corresponding URL parameter value from the request routing context.
+func (x *Context) URLParam(key string) string { return "" }
+
+// RoutePattern builds the routing pattern string for the particular request, at the particular point during routing.
+func (x *Context) RoutePattern() string { return "" }
+
+// RouteParams is a structure to track URL routing parameters efficiently.
+type RouteParams struct {
+	Keys, Values []string
+}
+
+// Add will append a URL parameter to the end of the route param.
+func (s *RouteParams) Add(key, value string) {}
+
+// RouteContext returns chi's routing Context object from a http.Request Context.
+func RouteContext(ctx context.Context) *Context { return nil }
+
+// URLParam returns the url parameter from a http.Request object.
+func URLParam(r *http.Request, key string) string { return "" }
@@ -0,0 +1,25 @@
+// Package endpoint is a stub of github.com/go-kit/kit/endpoint with its exported API.
+package endpoint
+
+import (
+	"context"
+)
+
+// Endpoint is the fundamental building block of servers and clients.
+type Endpoint func(ctx context.Context, request interface{}) (response interface{}, err error)
+
+// Nop is an endpoint that does nothing and returns a nil error.
+func Nop(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
+
+// Middleware is a chainable behavior modifier for endpoints.
+type Middleware func(Endpoint) Endpoint
+
+// Chain is a helper function for composing middlewares.
+func Chain(outer Middleware, others ...Middleware) Middleware {
+	return func(next Endpoint) Endpoint {
+		for i := len(others) - 1; i >= 0; i-- {
+			next = others[i](next)
+		}
+		return outer(next)
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// HTTPClient is an interface that models *http.Client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client wraps a URL and provides a method that implements endpoint.Endpoint.
+type Client struct {
+	client HTTPClient
+	method string
+	tgt    *url.URL
+	enc    EncodeRequestFunc
+	dec    DecodeResponseFunc
+	before []RequestFunc
+}
+
+// NewClient constructs a usable Client for a single remote method.
+func NewClient(method string, tgt *url.URL, enc EncodeRequestFunc, dec DecodeResponseFunc, options ...ClientOption) *Client {
+	c := &Client{client: http.DefaultClient, method: method, tgt: tgt, enc: enc, dec: dec}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// SetClient sets the underlying HTTP client used for requests.
+func SetClient(client HTTPClient) ClientOption {
+	return func(c *Client) { c.client = client }
+}
+
+// ClientBefore adds one or more RequestFuncs to be applied to the outgoing HTTP request before it's invoked.
+func ClientBefore(before ...RequestFunc) ClientOption {
+	return func(c *Client) { c.before = append(c.before, before...) }
+}
+
+// Endpoint returns a usable Go kit endpoint that calls the remote HTTP endpoint.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, err := http.NewRequest(c.method, c.tgt.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.enc(ctx, req, request); err != nil {
+			return nil, err
+		}
+		for _, f := range c.before {
+			ctx = f(ctx, req)
+		}
+		resp, err := c.client.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return c.dec(ctx, resp)
+	}
+}
@@ -0,0 +1,122 @@
+// Package http is a stub of github.com/go-kit/kit/transport/http with its exported API.
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from an HTTP request object.
+type DecodeRequestFunc func(context.Context, *http.Request) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the HTTP request object.
+type EncodeRequestFunc func(context.Context, *http.Request, interface{}) error
+
+// CreateRequestFunc creates an outgoing HTTP request based on the passed request object.
+type CreateRequestFunc func(context.Context, interface{}) (*http.Request, error)
+
+// EncodeResponseFunc encodes the passed response object to the HTTP response writer.
+type EncodeResponseFunc func(context.Context, http.ResponseWriter, interface{}) error
+
+// DecodeResponseFunc extracts a user-domain response object from an HTTP response object.
+type DecodeResponseFunc func(context.Context, *http.Response) (response interface{}, err error)
+
+// RequestFunc may take information from an HTTP request and put it into a request context.
+type RequestFunc func(context.Context, *http.Request) context.Context
+
+// ServerResponseFunc may take information from a request context and use it to manipulate a ResponseWriter.
+type ServerResponseFunc func(context.Context, http.ResponseWriter) context.Context
+
+// ClientResponseFunc may take information from an HTTP request and make the response available for consumption.
+type ClientResponseFunc func(context.Context, *http.Response) context.Context
+
+// ErrorEncoder is responsible for encoding an error to the ResponseWriter.
+type ErrorEncoder func(ctx context.Context, err error, w http.ResponseWriter)
+
+// StatusCoder is checked by DefaultErrorEncoder.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Headerer is checked by DefaultErrorEncoder.
+type Headerer interface {
+	Headers() http.Header
+}
+
+// Server wraps an endpoint and implements http.Handler.
+type Server struct {
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []RequestFunc
+	after        []ServerResponseFunc
+	errorEncoder ErrorEncoder
+}
+
+// NewServer constructs a new server, which implements http.Handler.
+func NewServer(e endpoint.Endpoint, dec DecodeRequestFunc, enc EncodeResponseFunc, options ...ServerOption) *Server {
+	s := &Server{e: e, dec: dec, enc: enc, errorEncoder: DefaultErrorEncoder}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the HTTP request object before the request is decoded.
+func ServerBefore(before ...RequestFunc) ServerOption {
+	return func(s *Server) { s.before = append(s.before, before...) }
+}
+
+// ServerAfter functions are executed on the HTTP response writer after the endpoint is invoked.
+func ServerAfter(after ...ServerResponseFunc) ServerOption {
+	return func(s *Server) { s.after = append(s.after, after...) }
+}
+
+// ServerErrorEncoder is used to encode errors to the http.ResponseWriter.
+func ServerErrorEncoder(ee ErrorEncoder) ServerOption {
+	return func(s *Server) { s.errorEncoder = ee }
+}
+
+// ServeHTTP implements http.Handler.
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	for _, f := range s.before {
+		ctx = f(ctx, r)
+	}
+	request, err := s.dec(ctx, r)
+	if err != nil {
+		s.errorEncoder(ctx, err, w)
+		return
+	}
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.errorEncoder(ctx, err, w)
+		return
+	}
+	for _, f := range s.after {
+		ctx = f(ctx, w)
+	}
+	if err := s.enc(ctx, w, response); err != nil {
+		s.errorEncoder(ctx, err, w)
+	}
+}
+
+// DefaultErrorEncoder writes the error to the ResponseWriter.
+func DefaultErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+	code := http.StatusInternalServerError
+	if sc, ok := err.(StatusCoder); ok {
+		code = sc.StatusCode()
+	}
+	w.WriteHeader(code)
+	w.Write([]byte(err.Error()))
+}
+
+// EncodeJSONResponse is an EncodeResponseFunc that serializes the response as a JSON object.
+func EncodeJSONResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	return nil
+}
@@ -0,0 +1,85 @@
+// Package mux is a stub of github.com/gorilla/mux with its exported API.
+package mux
+
+import (
+	"net/http"
+)
+
+// Router registers routes to be matched and dispatches a handler.
+type Router struct {
+	// NotFoundHandler to be used when no route matches.
+	NotFoundHandler http.Handler
+	// MethodNotAllowedHandler to be used when the request method does not match the route.
+	MethodNotAllowedHandler http.Handler
+}
+
+// NewRouter returns a new router instance.
+func NewRouter() *Router { return &Router{} }
+
+// ServeHTTP dispatches the handler registered in the matched route.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {}
+
+// Handle registers a new route with a matcher for the URL path.
+func (r *Router) Handle(path string, handler http.Handler) *Route { return &Route{} }
+
+// HandleFunc registers a new route with a matcher for the URL path.
+func (r *Router) HandleFunc(path string, f func(http.ResponseWriter, *http.Request)) *Route {
+	return &Route{}
+}
+
+// Match attempts to match the given request against the router's registered routes.
+func (r *Router) Match(req *http.Request, match *RouteMatch) bool { return false }
+
+// NewRoute registers an empty route.
+func (r *Router) NewRoute() *Route { return &Route{} }
+
+// Path registers a new route with a matcher for the URL path.
+func (r *Router) Path(tpl string) *Route { return &Route{} }
+
+// Methods registers a new route with a matcher for HTTP methods.
+func (r *Router) Methods(methods ...string) *Route { return &Route{} }
+
+// MiddlewareFunc is a function which receives an http.Handler and returns another http.Handler.
+type MiddlewareFunc func(http.Handler) http.Handler
+
+// Use appends a MiddlewareFunc to the chain.
+func (r *Router) Use(mwf ...MiddlewareFunc) {}
+
+// ErrMethodMismatch is returned when the method in the request does not match the method defined against the route.
+var ErrMethodMismatch error
+
+// ErrNotFound is returned when no route match is found.
+var ErrNotFound error
+
+// RouteMatch stores information about a matched route.
+type RouteMatch struct {
+	Route   *Route
+	Handler http.Handler
+	Vars    map[string]string
+	// MatchErr is set to appropriate matching error.
+	MatchErr error
+}
+
+// Route stores information to match a request and build URLs.
+type Route struct{}
+
+// Handler sets a handler for the route.
+func (r *Route) Handler(handler http.Handler) *Route { return r }
+
+// Methods adds a matcher for HTTP methods.
+func (r *Route) Methods(methods ...string) *Route { return r }
+
+// GetPathTemplate returns the template used to build the route match.
+func (r *Route) GetPathTemplate() (string, error) { return "", nil }
+
+// Name sets the name for the route, used to build URLs.
+func (r *Route) Name(name string) *Route { return r }
+
+// Path adds a matcher for the URL path.
+func (r *Route) Path(tpl string) *Route { return r }
+
+// Vars returns the route variables for the current request, if any.
+func Vars(r *http.Request) map[string]string { return nil }
+
+// SetURLVars sets the URL variables for the given request, to be accessed via mux.Vars for testing route behaviour.
+func SetURLVars(r *http.Request, val map[string]string) *http.Request { return r }
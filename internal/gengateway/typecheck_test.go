@@ -0,0 +1,251 @@
+package gengateway
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// TestGeneratedCodeTypeChecks type-checks the code generated for each generatorCase.
+//
+// The generated packages are checked together with the code protoc-gen-go generates for the same
+// protos, the hand-written companions a project would provide (testdata/companions) and stubs of
+// third party dependencies (testdata/stubs). Other packages are imported from their export data.
+func TestGeneratedCodeTypeChecks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping type-check in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not available")
+	}
+
+	for _, c := range generatorCases {
+		t.Run(c.name, func(t *testing.T) {
+			tc := newTypeChecker()
+			for _, f := range generatePB(t, c.protos) {
+				tc.addFile(t, path.Dir(f.GetName()), f.GetName(), f.GetContent())
+			}
+			for _, f := range generate(t, c) {
+				tc.addFile(t, path.Join(testModuleName, path.Dir(f.GetName())), f.GetName(), f.GetContent())
+			}
+			for _, dir := range companionDirs(c) {
+				tc.addDir(t, filepath.Join("testdata", "companions", dir), testModuleName)
+			}
+			tc.addDir(t, filepath.Join("testdata", "stubs"), "")
+
+			if err := tc.loadExports(); err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tc.paths() {
+				if !strings.HasPrefix(p, testModuleName+"/") {
+					continue
+				}
+				for _, err := range tc.check(p) {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+// companionDirs returns the directories in testdata/companions with the hand-written code of "c".
+func companionDirs(c generatorCase) []string {
+	// the hand-written ManualRouter depends on the router backend.
+	router := c.params.Router
+	if router == "" {
+		router = routerMux
+	}
+	return append([]string{"common", router}, c.companions...)
+}
+
+// generatePB returns the files protoc-gen-go generates for "protos".
+func generatePB(t *testing.T, protos []string) []*plugin.CodeGeneratorResponse_File {
+	gen, err := protogen.Options{}.New(loadRequest(t, protos))
+	if err != nil {
+		t.Fatalf("protogen.Options{}.New() failed with %v; want success", err)
+	}
+	for _, f := range gen.Files {
+		if f.Generate {
+			gengo.GenerateFile(gen, f)
+		}
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatalf("protoc-gen-go failed with %s", resp.GetError())
+	}
+	return resp.File
+}
+
+// typeChecker type-checks packages from in-memory sources.
+// It is an importer on its own, so that packages with sources can import each other.
+type typeChecker struct {
+	fset    *token.FileSet
+	sources map[string][]*ast.File
+	pkgs    map[string]*types.Package
+	errs    map[string][]error
+	// checking is the set of packages being type-checked, to detect import cycles.
+	checking map[string]bool
+	exports  map[string]string
+	gc       types.Importer
+}
+
+func newTypeChecker() *typeChecker {
+	tc := &typeChecker{
+		fset:     token.NewFileSet(),
+		sources:  make(map[string][]*ast.File),
+		pkgs:     make(map[string]*types.Package),
+		errs:     make(map[string][]error),
+		checking: make(map[string]bool),
+		exports:  make(map[string]string),
+	}
+	tc.gc = importer.ForCompiler(tc.fset, "gc", func(path string) (io.ReadCloser, error) {
+		export, ok := tc.exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	})
+	return tc
+}
+
+// addFile adds the source of a file in the package "importPath".
+// External test packages are added as "importPath" suffixed with "_test".
+func (tc *typeChecker) addFile(t *testing.T, importPath, name, src string) {
+	f, err := parser.ParseFile(tc.fset, name, src, 0)
+	if err != nil {
+		t.Fatalf("parser.ParseFile(%s) failed with %v; want success", name, err)
+	}
+	if strings.HasSuffix(f.Name.Name, "_test") {
+		importPath += "_test"
+	}
+	tc.sources[importPath] = append(tc.sources[importPath], f)
+}
+
+// addDir adds the go files in "root", using their directory relative to "root" under "prefix" as import path.
+func (tc *typeChecker) addDir(t *testing.T, root, prefix string) {
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && name == root {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(name))
+		if err != nil {
+			return err
+		}
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		tc.addFile(t, path.Join(prefix, filepath.ToSlash(rel)), name, string(src))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadExports locates the export data of the packages imported by the sources which are not in the sources.
+func (tc *typeChecker) loadExports() error {
+	imports := make(map[string]bool)
+	for _, files := range tc.sources {
+		for _, f := range files {
+			for _, spec := range f.Imports {
+				p, err := strconv.Unquote(spec.Path.Value)
+				if err != nil {
+					return err
+				}
+				if _, ok := tc.sources[p]; !ok {
+					imports[p] = true
+				}
+			}
+		}
+	}
+	if len(imports) == 0 {
+		return nil
+	}
+	args := []string{"list", "-export", "-deps", "-f", "{{.ImportPath}}={{.Export}}"}
+	for p := range imports {
+		args = append(args, p)
+	}
+	out, err := exec.Command("go", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("go list failed with %v: %s", err, exitErr.Stderr)
+		}
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		spec := strings.SplitN(line, "=", 2)
+		tc.exports[spec[0]] = spec[1]
+	}
+	return nil
+}
+
+// paths returns the import paths of the packages with sources.
+func (tc *typeChecker) paths() []string {
+	var result []string
+	for p := range tc.sources {
+		result = append(result, p)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// check type-checks the package "path" and returns the errors found in it.
+func (tc *typeChecker) check(path string) []error {
+	if _, err := tc.Import(path); err != nil && len(tc.errs[path]) == 0 {
+		return []error{err}
+	}
+	return tc.errs[path]
+}
+
+// Import implements types.Importer.
+func (tc *typeChecker) Import(path string) (*types.Package, error) {
+	if pkg, ok := tc.pkgs[path]; ok {
+		return pkg, nil
+	}
+	files, ok := tc.sources[path]
+	if !ok {
+		return tc.gc.Import(path)
+	}
+	if tc.checking[path] {
+		return nil, fmt.Errorf("import cycle through %s", path)
+	}
+	tc.checking[path] = true
+	defer delete(tc.checking, path)
+
+	conf := types.Config{
+		Importer: tc,
+		Error: func(err error) {
+			tc.errs[path] = append(tc.errs[path], err)
+		},
+	}
+	pkg, err := conf.Check(path, tc.fset, files, nil)
+	tc.pkgs[path] = pkg
+	if err != nil {
+		return pkg, fmt.Errorf("%s does not type-check", path)
+	}
+	return pkg, nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"strings"
 
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
@@ -11,10 +14,13 @@ import (
 	"github.com/thesoulless/protoc-gen-gokitmux/descriptor"
 
 	"github.com/golang/glog"
+	"github.com/thesoulless/protoc-gen-gokitmux/internal/diff"
 	"github.com/thesoulless/protoc-gen-gokitmux/internal/generator"
 	"github.com/thesoulless/protoc-gen-gokitmux/internal/gengateway"
+	gmoptions "github.com/thesoulless/protoc-gen-gokitmux/options"
 	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 
 	"os"
 
@@ -31,8 +37,53 @@ var (
 	metricsPackage             = flag.String("metrics", "", "path to metrics package")
 	generateService            = flag.Bool("gen_service", false, "should a service interface be generated")
 	errorEncoder               = flag.String("error_encoder", "", "sets error encoder name")
+	generateTests              = flag.Bool("gen_tests", false, "should httptest based tests of the routes be generated")
+	generateFake               = flag.Bool("gen_fake", false, "should a fake GatewayService be generated")
+	splitServices              = flag.Bool("split_services", false, "should an interface and route registration be generated per proto service. Implies gen_service")
+	grpcClient                 = flag.Bool("grpc_client", false, "should adapters forwarding the services to gRPC clients be generated")
+	grpcServer                 = flag.Bool("grpc_server", false, "should adapters calling gRPC servers in-process be generated")
+	router                     = flag.String("router", "mux", "router backend the routes are registered with. Allowed values are `mux`, `servemux` and `chi`")
+	transport                  = flag.String("transport", "gokit", "what the handlers are built with. Allowed values are `gokit` and `nethttp`, plain net/http handlers without go-kit")
+	emitUnpopulated            = flag.Bool("emit_unpopulated", false, "should the JSON marshaler emit fields with default values")
+	useProtoNames              = flag.Bool("use_proto_names", false, "should the JSON marshaler name fields after their proto names instead of their JSON names")
+	useEnumNumbers             = flag.Bool("use_enum_numbers", false, "should the JSON marshaler emit enums as numbers instead of names")
+	discardUnknown             = flag.Bool("discard_unknown", false, "should the JSON and form marshalers ignore unknown fields instead of rejecting the request")
+	multipart                  = flag.Bool("multipart", false, "should multipart/form-data bodies be decoded by gen.DecodeMessage, their files into bytes fields, and gen.StreamMultipart")
+	validate                   = flag.Bool("validate", false, "should the handlers validate the decoded requests with their Validate methods, or gen.Validator, before calling the service")
+	rejectImmutable            = flag.Bool("reject_immutable", false, "should the handlers of partial updates reject the update masks masking IMMUTABLE fields of the body")
+	negotiateAccept            = flag.Bool("negotiate_accept", false, "should the handlers reject the requests whose Accept header no registered marshaler can answer before calling the service. Needs Encode methods writing the responses with gen.EncodeMessage")
+	successStatus              = flag.String("success_status", "aip", "how the status codes of successful responses are selected. Allowed values are `aip`, inferring 201 Created and 204 No Content from the standard methods, and `ok` for 200 OK")
+	corsCredentials            = flag.Bool("cors_credentials", false, "should the callers allowed by cors_origin be allowed to send credentials, e.g. cookies")
+	corsMaxAge                 = flag.Int("cors_max_age", 0, "how long the preflight responses may be cached, in seconds")
+	requireUnimplemented       = flag.Bool("require_unimplemented", false, "should GatewayService implementations be required to embed UnimplementedGatewayService. Needs gen_service")
+	showVersion                = flag.Bool("version", false, "print version information and exit")
 )
 
+// The CORS policy of the routes without a gokitmux.cors or gokitmux.service_cors option. The flags can be repeated.
+var (
+	corsOrigins        stringsFlag
+	corsHeaders        stringsFlag
+	corsExposedHeaders stringsFlag
+)
+
+func init() {
+	flag.Var(&corsOrigins, "cors_origin", "origin allowed to call the routes, e.g. `https://example.com`, or * for any origin. Repeat it to allow several")
+	flag.Var(&corsHeaders, "cors_header", "request header the callers allowed by cors_origin may send, or * for any header. Repeat it to allow several")
+	flag.Var(&corsExposedHeaders, "cors_exposed_header", "response header the callers allowed by cors_origin may read. Repeat it to expose several")
+}
+
+// stringsFlag is a flag collecting the values it is set to.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 // Variables set by goreleaser at build time
 var (
 	version = "dev"
@@ -41,8 +92,22 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		defer glog.Flush()
+		if err := runGenerate(os.Args[2:]); err != nil {
+			glog.Flush()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.Parse()
 	defer glog.Flush()
+	if *showVersion {
+		printVersion()
+		return
+	}
 	reg := descriptor.NewRegistry()
 
 	glog.V(1).Info("Parsing code generator request")
@@ -56,80 +121,239 @@ func main() {
 
 		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
 
-		if req.Parameter != nil {
-			for _, p := range strings.Split(req.GetParameter(), ",") {
-				spec := strings.SplitN(p, "=", 2)
-				if len(spec) == 1 {
-					if err := flag.CommandLine.Set(spec[0], ""); err != nil {
-						glog.Fatalf("Cannot set flag %s", p)
-					}
-					continue
-				}
-				name, value := spec[0], spec[1]
-				if strings.HasPrefix(name, "M") {
-					reg.AddPkgMap(name[1:], value)
-					continue
-				}
-				if err := flag.CommandLine.Set(name, value); err != nil {
-					glog.Fatalf("Cannot set flag %s", p)
-				}
-			}
+		out, err := generate(reg, req)
+		glog.V(1).Info("Processed code generator request")
+		if err != nil {
+			emitError(err)
+			return err
 		}
+		emitFiles(out)
 
-		if *grpcAPIConfiguration != "" {
-			if err = reg.LoadGrpcAPIServiceFromYAML(*grpcAPIConfiguration); err != nil {
-				emitError(err)
-				panic(1)
-			}
+		return nil
+	})
+}
+
+// generate applies the parameters of "req" to the command line flags, loads "req" into "reg"
+// and runs the gateway generator against the files to generate.
+func generate(reg *descriptor.Registry, req *plugin.CodeGeneratorRequest) ([]*plugin.CodeGeneratorResponse_File, error) {
+	if req.Parameter != nil {
+		if err := parseParameter(reg, req.GetParameter()); err != nil {
+			return nil, err
 		}
+	}
 
-		reg.SetPrefix(*importPrefix)
-		reg.SetImportPath(*importPath)
-		if err = reg.SetRepeatedPathParamSeparator(*repeatedPathParamSeparator); err != nil {
-			emitError(err)
-			panic(1)
+	if *grpcAPIConfiguration != "" {
+		if err := reg.LoadGrpcAPIServiceFromYAML(*grpcAPIConfiguration); err != nil {
+			return nil, err
 		}
-		if err = reg.Load(req); err != nil {
-			emitError(err)
-			panic(1)
+	}
+
+	reg.SetPrefix(*importPrefix)
+	reg.SetImportPath(*importPath)
+	if err := reg.SetRepeatedPathParamSeparator(*repeatedPathParamSeparator); err != nil {
+		return nil, err
+	}
+	if err := reg.Load(req); err != nil {
+		return nil, err
+	}
+	unboundHTTPRules := reg.UnboundExternalHTTPRules()
+	if len(unboundHTTPRules) != 0 {
+		return nil, fmt.Errorf("HTTP rules without a matching selector: %s", strings.Join(unboundHTTPRules, ", "))
+	}
+
+	var targets []*descriptor.File
+	for _, target := range req.FileToGenerate {
+		f, err := reg.LookupFile(target)
+		if err != nil {
+			return nil, err
 		}
-		unboundHTTPRules := reg.UnboundExternalHTTPRules()
-		if len(unboundHTTPRules) != 0 {
-			emitError(fmt.Errorf("HTTP rules without a matching selector: %s", strings.Join(unboundHTTPRules, ", ")))
-			panic(1)
+		targets = append(targets, f)
+	}
+
+	cors, err := corsPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	packageName := strings.Split(*modulePath, "/")
+	PackageName := packageName[len(packageName)-1]
+
+	ps := generator.Params{
+		GenerateService:      *generateService,
+		MetricsPackage:       *metricsPackage,
+		ErrorEncoder:         *errorEncoder,
+		PackageName:          PackageName,
+		RegisterFuncSuffix:   *registerFuncSuffix,
+		GenerateTests:        *generateTests,
+		GenerateFake:         *generateFake,
+		RequireUnimplemented: *requireUnimplemented,
+		SplitServices:        *splitServices,
+		GRPCClient:           *grpcClient,
+		GRPCServer:           *grpcServer,
+		Router:               *router,
+		Transport:            *transport,
+		EmitUnpopulated:      *emitUnpopulated,
+		UseProtoNames:        *useProtoNames,
+		UseEnumNumbers:       *useEnumNumbers,
+		DiscardUnknown:       *discardUnknown,
+		Multipart:            *multipart,
+		Validate:             *validate,
+		RejectImmutable:      *rejectImmutable,
+		NegotiateAccept:      *negotiateAccept,
+		SuccessStatus:        *successStatus,
+		CORS:                 cors,
+	}
+
+	gwGen := gengateway.New(reg, *modulePath)
+	return gwGen.Generate(targets, ps)
+}
+
+// corsPolicy returns the CORS policy of the cors_ flags, or nil if no origin is allowed.
+func corsPolicy() (*gmoptions.CORSPolicy, error) {
+	if len(corsOrigins) == 0 {
+		if len(corsHeaders) != 0 || len(corsExposedHeaders) != 0 || *corsCredentials || *corsMaxAge != 0 {
+			return nil, errors.New("cors_header, cors_exposed_header, cors_credentials and cors_max_age need cors_origin")
 		}
+		return nil, nil
+	}
+	return &gmoptions.CORSPolicy{
+		AllowedOrigins:   corsOrigins,
+		AllowedHeaders:   corsHeaders,
+		ExposedHeaders:   corsExposedHeaders,
+		AllowCredentials: *corsCredentials,
+		MaxAge:           int32(*corsMaxAge),
+	}, nil
+}
 
-		var targets []*descriptor.File
-		for _, target := range req.FileToGenerate {
-			f, _err := reg.LookupFile(target)
-			if _err != nil {
-				glog.Fatal(err)
+// parseParameter sets the command line flags from a comma separated plugin parameter.
+// Parameters starting with "M" are registered as package mappings in "reg".
+func parseParameter(reg *descriptor.Registry, parameter string) error {
+	for _, p := range strings.Split(parameter, ",") {
+		spec := strings.SplitN(p, "=", 2)
+		if len(spec) == 1 {
+			if err := flag.CommandLine.Set(spec[0], ""); err != nil {
+				return fmt.Errorf("Cannot set flag %s", p)
 			}
-			targets = append(targets, f)
+			continue
+		}
+		name, value := spec[0], spec[1]
+		if strings.HasPrefix(name, "M") {
+			reg.AddPkgMap(name[1:], value)
+			continue
+		}
+		if err := flag.CommandLine.Set(name, value); err != nil {
+			return fmt.Errorf("Cannot set flag %s", p)
 		}
+	}
+	return nil
+}
 
-		packageName := strings.Split(*modulePath, "/")
-		PackageName := packageName[len(packageName)-1]
+// glogFlags are the flags glog registers on the command line, accepted by the generate subcommand too.
+var glogFlags = map[string]bool{
+	"v":                true,
+	"vmodule":          true,
+	"logtostderr":      true,
+	"alsologtostderr":  true,
+	"stderrthreshold":  true,
+	"log_dir":          true,
+	"log_backtrace_at": true,
+}
 
-		ps := generator.Params{
-			GenerateService:    *generateService,
-			MetricsPackage:     *metricsPackage,
-			ErrorEncoder:       *errorEncoder,
-			PackageName:        PackageName,
-			RegisterFuncSuffix: *registerFuncSuffix,
+// runGenerate runs the generator outside of protoc, reading a serialized FileDescriptorSet
+// (e.g. produced by `protoc --include_imports -o api.pb`) and writing the generated files to disk.
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	descriptorSet := fs.String("descriptor_set", "", "path to a serialized FileDescriptorSet")
+	outDir := fs.String("out", ".", "directory generated files are written to")
+	parameter := fs.String("param", "", "plugin parameter, in the same format as passed to --gokitmux_out")
+	files := fs.String("files", "", "comma separated proto files to generate. Defaults to every file in the set declaring a service")
+	dryRun := fs.Bool("dry-run", false, "print the generated files and their diffs against existing outputs instead of writing them")
+	showVersion := fs.Bool("version", false, "print version information and exit")
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if glogFlags[f.Name] {
+			fs.Var(f.Value, f.Name, f.Usage)
 		}
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// glog only logs once the command line has been parsed; its flags were set through fs.
+	if err := flag.CommandLine.Parse(nil); err != nil {
+		return err
+	}
+	if *showVersion {
+		printVersion()
+		return nil
+	}
+	if *descriptorSet == "" {
+		return fmt.Errorf("--descriptor_set is required")
+	}
 
-		gwGen := gengateway.New(*modulePath)
-		out, err := gwGen.Generate(targets, ps)
-		glog.V(1).Info("Processed code generator request")
-		if err != nil {
-			emitError(err)
+	buf, err := ioutil.ReadFile(*descriptorSet)
+	if err != nil {
+		return fmt.Errorf("failed to read descriptor set %s: %v", *descriptorSet, err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(buf, &set); err != nil {
+		return fmt.Errorf("failed to parse descriptor set %s: %v", *descriptorSet, err)
+	}
+
+	req := &plugin.CodeGeneratorRequest{
+		ProtoFile: set.GetFile(),
+	}
+	if *parameter != "" {
+		req.Parameter = parameter
+	}
+	if *files != "" {
+		req.FileToGenerate = strings.Split(*files, ",")
+	} else {
+		for _, f := range set.GetFile() {
+			if len(f.GetService()) > 0 {
+				req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+			}
+		}
+	}
+	if len(req.FileToGenerate) == 0 {
+		return fmt.Errorf("no files to generate in %s", *descriptorSet)
+	}
+
+	out, err := generate(descriptor.NewRegistry(), req)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range out {
+		name := filepath.Join(*outDir, f.GetName())
+		if *dryRun {
+			old, err := ioutil.ReadFile(name)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			switch {
+			case err != nil:
+				fmt.Printf("A %s\n", name)
+			case string(old) == f.GetContent():
+				fmt.Printf("  %s\n", name)
+				continue
+			default:
+				fmt.Printf("M %s\n", name)
+			}
+			fmt.Print(diff.Unified("a/"+f.GetName(), "b/"+f.GetName(), string(old), f.GetContent()))
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
 			return err
 		}
-		emitFiles(out)
+		if err := ioutil.WriteFile(name, []byte(f.GetContent()), 0644); err != nil {
+			return err
+		}
+		glog.V(1).Infof("Wrote %s", name)
+	}
+	return nil
+}
 
-		return nil
-	})
+func printVersion() {
+	fmt.Printf("protoc-gen-gokitmux %s (commit %s, built %s)\n", version, commit, date)
 }
 
 func emitFiles(out []*plugin.CodeGeneratorResponse_File) {
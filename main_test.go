@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gokitmux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the descriptor set of the hello and bye protos, written by the tests of gengateway.
+	set := filepath.Join("internal", "gengateway", "testdata", "descriptor_set.pb")
+	out := filepath.Join(dir, "out")
+	routes := filepath.Join(out, "gen", "routes.gm.go")
+
+	if _, err := captureStdout(t, func() error { return runGenerate(nil) }); err == nil {
+		t.Errorf("runGenerate() without --descriptor_set succeeded; want an error")
+	}
+
+	stdout, err := captureStdout(t, func() error { return runGenerate([]string{"--version"}) })
+	if err != nil {
+		t.Fatalf("runGenerate(--version) failed with %v; want success", err)
+	}
+	if want := "protoc-gen-gokitmux " + version; !strings.HasPrefix(stdout, want) {
+		t.Errorf("runGenerate(--version) printed %q; want it to start with %q", stdout, want)
+	}
+
+	v := flag.Lookup("v")
+	defer v.Value.Set(v.Value.String())
+	if _, err := captureStdout(t, func() error { return runGenerate([]string{"-v=2", "--version"}) }); err != nil {
+		t.Fatalf("runGenerate(-v=2, --version) failed with %v; want success", err)
+	}
+	if got := v.Value.String(); got != "2" {
+		t.Errorf("runGenerate(-v=2) left the glog verbosity at %q; want %q", got, "2")
+	}
+
+	args := []string{"--descriptor_set", set, "--out", out, "--param", "module=gen"}
+	stdout, err = captureStdout(t, func() error { return runGenerate(append(args, "--dry-run")) })
+	if err != nil {
+		t.Fatalf("runGenerate(--dry-run) failed with %v; want success", err)
+	}
+	if want := "A " + routes + "\n"; !strings.Contains(stdout, want) {
+		t.Errorf("runGenerate(--dry-run) printed %q; want it to contain %q", stdout, want)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("runGenerate(--dry-run) wrote %s", out)
+	}
+
+	if _, err := captureStdout(t, func() error { return runGenerate(args) }); err != nil {
+		t.Fatalf("runGenerate() failed with %v; want success", err)
+	}
+	generated, err := ioutil.ReadFile(routes)
+	if err != nil {
+		t.Fatalf("runGenerate() did not write %s: %v", routes, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "gen", "hello", "hello.gm.go")); err != nil {
+		t.Errorf("runGenerate() did not write the handlers of hello: %v", err)
+	}
+
+	if err := ioutil.WriteFile(routes, []byte(strings.Replace(string(generated), "func Router(", "func OldRouter(", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, err = captureStdout(t, func() error { return runGenerate(append(args, "--dry-run")) })
+	if err != nil {
+		t.Fatalf("runGenerate(--dry-run) failed with %v; want success", err)
+	}
+	for _, want := range []string{
+		"M " + routes + "\n",
+		"--- a/gen/routes.gm.go\n+++ b/gen/routes.gm.go\n",
+		"-func OldRouter(",
+		"+func Router(",
+		"  " + filepath.Join(out, "gen", "hello", "hello.gm.go") + "\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("runGenerate(--dry-run) printed %q; want it to contain %q", stdout, want)
+		}
+	}
+}
+
+// captureStdout returns what "f" prints to the standard output, and the error it returns.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		buf, _ := ioutil.ReadAll(r)
+		done <- buf
+	}()
+	err = f()
+	os.Stdout = stdout
+	w.Close()
+	return string(<-done), err
+}
@@ -0,0 +1,488 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: options/options.proto
+
+package options
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FieldBinding binds a field of a request message to an HTTP header or cookie.
+type FieldBinding struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Source:
+	//	*FieldBinding_Header
+	//	*FieldBinding_Cookie
+	Source isFieldBinding_Source `protobuf_oneof:"source"`
+	// Whether requests without the header or cookie are rejected with 400 Bad Request.
+	Required bool `protobuf:"varint,3,opt,name=required,proto3" json:"required,omitempty"`
+}
+
+func (x *FieldBinding) Reset() {
+	*x = FieldBinding{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_options_options_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FieldBinding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldBinding) ProtoMessage() {}
+
+func (x *FieldBinding) ProtoReflect() protoreflect.Message {
+	mi := &file_options_options_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldBinding.ProtoReflect.Descriptor instead.
+func (*FieldBinding) Descriptor() ([]byte, []int) {
+	return file_options_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (m *FieldBinding) GetSource() isFieldBinding_Source {
+	if m != nil {
+		return m.Source
+	}
+	return nil
+}
+
+func (x *FieldBinding) GetHeader() string {
+	if x, ok := x.GetSource().(*FieldBinding_Header); ok {
+		return x.Header
+	}
+	return ""
+}
+
+func (x *FieldBinding) GetCookie() string {
+	if x, ok := x.GetSource().(*FieldBinding_Cookie); ok {
+		return x.Cookie
+	}
+	return ""
+}
+
+func (x *FieldBinding) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+type isFieldBinding_Source interface {
+	isFieldBinding_Source()
+}
+
+type FieldBinding_Header struct {
+	// The name of the HTTP header the field is read from.
+	Header string `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type FieldBinding_Cookie struct {
+	// The name of the cookie the field is read from.
+	Cookie string `protobuf:"bytes,2,opt,name=cookie,proto3,oneof"`
+}
+
+func (*FieldBinding_Header) isFieldBinding_Source() {}
+
+func (*FieldBinding_Cookie) isFieldBinding_Source() {}
+
+// ResponseStatus sets the status code of the successful responses of a method.
+type ResponseStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The status code, e.g. 202 for Accepted. Responses with 204 No Content have no body.
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// The field of the response message holding the name of the resource the method creates,
+	// which the Location header of the responses is built from.
+	LocationField string `protobuf:"bytes,2,opt,name=location_field,json=locationField,proto3" json:"location_field,omitempty"`
+}
+
+func (x *ResponseStatus) Reset() {
+	*x = ResponseStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_options_options_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResponseStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseStatus) ProtoMessage() {}
+
+func (x *ResponseStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_options_options_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseStatus.ProtoReflect.Descriptor instead.
+func (*ResponseStatus) Descriptor() ([]byte, []int) {
+	return file_options_options_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ResponseStatus) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ResponseStatus) GetLocationField() string {
+	if x != nil {
+		return x.LocationField
+	}
+	return ""
+}
+
+// CORSPolicy sets how the routes of a service or a method answer cross-origin requests.
+type CORSPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The origins allowed to call the routes, e.g. "https://example.com", or "*" for any origin.
+	// Routes with no allowed origins answer no cross-origin request.
+	AllowedOrigins []string `protobuf:"bytes,1,rep,name=allowed_origins,json=allowedOrigins,proto3" json:"allowed_origins,omitempty"`
+	// The request headers the callers may send besides the CORS-safelisted ones, or "*" for any header.
+	AllowedHeaders []string `protobuf:"bytes,2,rep,name=allowed_headers,json=allowedHeaders,proto3" json:"allowed_headers,omitempty"`
+	// The response headers the callers may read besides the CORS-safelisted ones.
+	ExposedHeaders []string `protobuf:"bytes,3,rep,name=exposed_headers,json=exposedHeaders,proto3" json:"exposed_headers,omitempty"`
+	// Whether the callers may send credentials, e.g. cookies.
+	AllowCredentials bool `protobuf:"varint,4,opt,name=allow_credentials,json=allowCredentials,proto3" json:"allow_credentials,omitempty"`
+	// How long the preflight responses may be cached, in seconds.
+	MaxAge int32 `protobuf:"varint,5,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+}
+
+func (x *CORSPolicy) Reset() {
+	*x = CORSPolicy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_options_options_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CORSPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CORSPolicy) ProtoMessage() {}
+
+func (x *CORSPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_options_options_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CORSPolicy.ProtoReflect.Descriptor instead.
+func (*CORSPolicy) Descriptor() ([]byte, []int) {
+	return file_options_options_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CORSPolicy) GetAllowedOrigins() []string {
+	if x != nil {
+		return x.AllowedOrigins
+	}
+	return nil
+}
+
+func (x *CORSPolicy) GetAllowedHeaders() []string {
+	if x != nil {
+		return x.AllowedHeaders
+	}
+	return nil
+}
+
+func (x *CORSPolicy) GetExposedHeaders() []string {
+	if x != nil {
+		return x.ExposedHeaders
+	}
+	return nil
+}
+
+func (x *CORSPolicy) GetAllowCredentials() bool {
+	if x != nil {
+		return x.AllowCredentials
+	}
+	return false
+}
+
+func (x *CORSPolicy) GetMaxAge() int32 {
+	if x != nil {
+		return x.MaxAge
+	}
+	return 0
+}
+
+var file_options_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*FieldBinding)(nil),
+		Field:         50780,
+		Name:          "gokitmux.binding",
+		Tag:           "bytes,50780,opt,name=binding",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*ResponseStatus)(nil),
+		Field:         50781,
+		Name:          "gokitmux.status",
+		Tag:           "bytes,50781,opt,name=status",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*CORSPolicy)(nil),
+		Field:         50782,
+		Name:          "gokitmux.service_cors",
+		Tag:           "bytes,50782,opt,name=service_cors",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*CORSPolicy)(nil),
+		Field:         50783,
+		Name:          "gokitmux.cors",
+		Tag:           "bytes,50783,opt,name=cors",
+		Filename:      "options/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Binds a top-level scalar field of a request message to an HTTP header or cookie,
+	// besides the path, body and query bound by the google.api.http option of the method.
+	//
+	// optional gokitmux.FieldBinding binding = 50780;
+	E_Binding = &file_options_options_proto_extTypes[0]
+)
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// Sets the status code of the successful responses of a method, instead of the one
+	// inferred by the success_status mode of the generator.
+	//
+	// optional gokitmux.ResponseStatus status = 50781;
+	E_Status = &file_options_options_proto_extTypes[1]
+	// Sets the CORS policy of the routes of a method, instead of the one of its service.
+	//
+	// optional gokitmux.CORSPolicy cors = 50783;
+	E_Cors = &file_options_options_proto_extTypes[3]
+)
+
+// Extension fields to descriptorpb.ServiceOptions.
+var (
+	// Sets the CORS policy of the routes of a service, instead of the one of the generator parameters.
+	//
+	// optional gokitmux.CORSPolicy service_cors = 50782;
+	E_ServiceCors = &file_options_options_proto_extTypes[2]
+)
+
+var File_options_options_proto protoreflect.FileDescriptor
+
+var file_options_options_proto_rawDesc = []byte{
+	0x0a, 0x15, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x67, 0x6f, 0x6b, 0x69, 0x74, 0x6d, 0x75,
+	0x78, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0x68, 0x0a, 0x0c, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x42, 0x69, 0x6e, 0x64,
+	0x69, 0x6e, 0x67, 0x12, 0x18, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a,
+	0x06, 0x63, 0x6f, 0x6f, 0x6b, 0x69, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
+	0x06, 0x63, 0x6f, 0x6f, 0x6b, 0x69, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x69,
+	0x72, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x69,
+	0x72, 0x65, 0x64, 0x42, 0x08, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x22, 0x4b, 0x0a,
+	0x0e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63,
+	0x6f, 0x64, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f,
+	0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6c, 0x6f, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x22, 0xcd, 0x01, 0x0a, 0x0a, 0x43,
+	0x4f, 0x52, 0x53, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x27, 0x0a, 0x0f, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x65, 0x64, 0x5f, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x0e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x4f, 0x72, 0x69, 0x67, 0x69,
+	0x6e, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x68, 0x65,
+	0x61, 0x64, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x65, 0x64, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x65,
+	0x78, 0x70, 0x6f, 0x73, 0x65, 0x64, 0x5f, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x65, 0x78, 0x70, 0x6f, 0x73, 0x65, 0x64, 0x48, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x73, 0x12, 0x2b, 0x0a, 0x11, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x63, 0x72,
+	0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x10, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c,
+	0x73, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41, 0x67, 0x65, 0x3a, 0x51, 0x0a, 0x07, 0x62, 0x69,
+	0x6e, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0xdc, 0x8c, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67,
+	0x6f, 0x6b, 0x69, 0x74, 0x6d, 0x75, 0x78, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x42, 0x69, 0x6e,
+	0x64, 0x69, 0x6e, 0x67, 0x52, 0x07, 0x62, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x3a, 0x52, 0x0a,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64,
+	0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xdd, 0x8c, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x18, 0x2e, 0x67, 0x6f, 0x6b, 0x69, 0x74, 0x6d, 0x75, 0x78, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x3a, 0x5a, 0x0a, 0x0c, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x72,
+	0x73, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0xde, 0x8c, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6b,
+	0x69, 0x74, 0x6d, 0x75, 0x78, 0x2e, 0x43, 0x4f, 0x52, 0x53, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79,
+	0x52, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x43, 0x6f, 0x72, 0x73, 0x3a, 0x4a, 0x0a,
+	0x04, 0x63, 0x6f, 0x72, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xdf, 0x8c, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e,
+	0x67, 0x6f, 0x6b, 0x69, 0x74, 0x6d, 0x75, 0x78, 0x2e, 0x43, 0x4f, 0x52, 0x53, 0x50, 0x6f, 0x6c,
+	0x69, 0x63, 0x79, 0x52, 0x04, 0x63, 0x6f, 0x72, 0x73, 0x42, 0x34, 0x5a, 0x32, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x68, 0x65, 0x73, 0x6f, 0x75, 0x6c, 0x6c,
+	0x65, 0x73, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x67,
+	0x6f, 0x6b, 0x69, 0x74, 0x6d, 0x75, 0x78, 0x2f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_options_options_proto_rawDescOnce sync.Once
+	file_options_options_proto_rawDescData = file_options_options_proto_rawDesc
+)
+
+func file_options_options_proto_rawDescGZIP() []byte {
+	file_options_options_proto_rawDescOnce.Do(func() {
+		file_options_options_proto_rawDescData = protoimpl.X.CompressGZIP(file_options_options_proto_rawDescData)
+	})
+	return file_options_options_proto_rawDescData
+}
+
+var file_options_options_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_options_options_proto_goTypes = []interface{}{
+	(*FieldBinding)(nil),                // 0: gokitmux.FieldBinding
+	(*ResponseStatus)(nil),              // 1: gokitmux.ResponseStatus
+	(*CORSPolicy)(nil),                  // 2: gokitmux.CORSPolicy
+	(*descriptorpb.FieldOptions)(nil),   // 3: google.protobuf.FieldOptions
+	(*descriptorpb.MethodOptions)(nil),  // 4: google.protobuf.MethodOptions
+	(*descriptorpb.ServiceOptions)(nil), // 5: google.protobuf.ServiceOptions
+}
+var file_options_options_proto_depIdxs = []int32{
+	3, // 0: gokitmux.binding:extendee -> google.protobuf.FieldOptions
+	4, // 1: gokitmux.status:extendee -> google.protobuf.MethodOptions
+	5, // 2: gokitmux.service_cors:extendee -> google.protobuf.ServiceOptions
+	4, // 3: gokitmux.cors:extendee -> google.protobuf.MethodOptions
+	0, // 4: gokitmux.binding:type_name -> gokitmux.FieldBinding
+	1, // 5: gokitmux.status:type_name -> gokitmux.ResponseStatus
+	2, // 6: gokitmux.service_cors:type_name -> gokitmux.CORSPolicy
+	2, // 7: gokitmux.cors:type_name -> gokitmux.CORSPolicy
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	4, // [4:8] is the sub-list for extension type_name
+	0, // [0:4] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_options_options_proto_init() }
+func file_options_options_proto_init() {
+	if File_options_options_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_options_options_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FieldBinding); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_options_options_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResponseStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_options_options_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CORSPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_options_options_proto_msgTypes[0].OneofWrappers = []interface{}{
+		(*FieldBinding_Header)(nil),
+		(*FieldBinding_Cookie)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_options_options_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 4,
+			NumServices:   0,
+		},
+		GoTypes:           file_options_options_proto_goTypes,
+		DependencyIndexes: file_options_options_proto_depIdxs,
+		MessageInfos:      file_options_options_proto_msgTypes,
+		ExtensionInfos:    file_options_options_proto_extTypes,
+	}.Build()
+	File_options_options_proto = out.File
+	file_options_options_proto_rawDesc = nil
+	file_options_options_proto_goTypes = nil
+	file_options_options_proto_depIdxs = nil
+}